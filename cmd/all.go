@@ -9,12 +9,20 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var fromSnapshot string
+
 var allCmd = &cobra.Command{
 	Use:   "all",
 	Short: "Check all conditions of all api-resources",
 	Long:  `...`,
 	Run: func(cmd *cobra.Command, args []string) {
-		unhealthy, err := checkconditions.RunAllOnce(context.Background(), &arguments)
+		var unhealthy bool
+		var err error
+		if fromSnapshot != "" {
+			unhealthy, err = checkconditions.RunFromSnapshot(&arguments, fromSnapshot)
+		} else {
+			unhealthy, err = checkconditions.RunAllOnce(context.Background(), &arguments)
+		}
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(3)
@@ -27,5 +35,8 @@ var allCmd = &cobra.Command{
 }
 
 func init() {
+	allCmd.Flags().StringVar(&arguments.WriteBaselineFile, "write-baseline", "", "Record the findings in this file (without durations). Use it later with --baseline.")
+	allCmd.Flags().StringVar(&arguments.BaselineFile, "baseline", "", "Only findings which are not in this file (see --write-baseline) make the exit code 1. New, still present and resolved findings are printed separately.")
+	allCmd.Flags().StringVar(&fromSnapshot, "from-snapshot", "", "Check the objects of an archive written by the 'snapshot' sub-command instead of the cluster. Durations are relative to the capture time.")
 	rootCmd.AddCommand(allCmd)
 }
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/guettli/check-conditions/pkg/checkconditions"
+	"github.com/spf13/cobra"
+)
+
+var checkFilesNow string
+
+var checkFilesCmd = &cobra.Command{
+	Use:   "check-files file-or-directory...",
+	Short: "Check all conditions of objects in YAML/JSON files, directories or stdin ('-'). Output and exit codes are the same as 'all'.",
+	Example: `  kubectl get -A -o yaml pods,nodes > dump.yaml
+  check-conditions check-files dump.yaml
+  check-conditions check-files manifests/ --now 2024-05-01T10:00:00Z
+  kubectl get -A -o yaml machines | check-conditions check-files -`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if checkFilesNow != "" {
+			now, err := time.Parse(time.RFC3339, checkFilesNow)
+			if err != nil {
+				fmt.Printf("invalid --now %q: %v\n", checkFilesNow, err)
+				os.Exit(3)
+			}
+			arguments.Now = now
+		}
+		unhealthy, err := checkconditions.RunCheckFiles(&arguments, args, os.Stdin)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		if unhealthy {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	checkFilesCmd.Flags().StringVar(&checkFilesNow, "now", "", "Reference time (RFC3339) for durations and the deletionTimestamp age. Default: the current time. Useful for dumps of clusters which no longer exist.")
+	rootCmd.AddCommand(checkFilesCmd)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/guettli/check-conditions/pkg/checkconditions"
+	"github.com/spf13/cobra"
+)
+
+var explainFilenames []string
+
+var explainCmd = &cobra.Command{
+	Use:   "explain resource [namespace/]name",
+	Short: "Show which classification step decided about each condition of an object, and the resulting findings.",
+	Example: `  check-conditions explain machines.cluster.x-k8s.io default/demo-1-md-0-q9qzp-6gsw9-vkxrp
+  check-conditions explain nodes node-1
+  check-conditions explain -f machine.yaml`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(explainFilenames) > 0 {
+			return cobra.NoArgs(cmd, args)
+		}
+		if len(args) != 2 {
+			return errors.New("expected resource and name, or --filename")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		if len(explainFilenames) > 0 {
+			err = checkconditions.RunExplainFiles(&arguments, explainFilenames, os.Stdin, os.Stdout)
+		} else {
+			err = checkconditions.RunExplain(context.Background(), &arguments, args[0], args[1], os.Stdout)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	explainCmd.Flags().StringSliceVarP(&explainFilenames, "filename", "f", nil, "Explain the objects of these files or directories ('-' for stdin) instead of an object of the cluster.")
+	rootCmd.AddCommand(explainCmd)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/guettli/check-conditions/pkg/checkconditions"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inventoryOutput       string
+	inventoryFromSnapshot string
+	inventoryTopReasons   int
+)
+
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Count all conditions per resource type, condition type and status, and show how the current rules treat them (healthy, unhealthy or skipped).",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := checkconditions.RunInventory(context.Background(), &arguments, inventoryFromSnapshot, inventoryOutput, inventoryTopReasons)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	inventoryCmd.Flags().StringVarP(&inventoryOutput, "output", "o", checkconditions.InventoryTable, "Output format: table or json.")
+	inventoryCmd.Flags().StringVar(&inventoryFromSnapshot, "from-snapshot", "", "Read the objects from an archive written by the 'snapshot' sub-command instead of the cluster.")
+	inventoryCmd.Flags().IntVar(&inventoryTopReasons, "top-reasons", 3, "Number of reasons shown per condition type and status. 0 shows all.")
+	rootCmd.AddCommand(inventoryCmd)
+}
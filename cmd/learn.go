@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/guettli/check-conditions/pkg/checkconditions"
+	"github.com/spf13/cobra"
+)
+
+var learnOptions checkconditions.LearnOptions
+
+var learnCmd = &cobra.Command{
+	Use:   "learn rules.yaml",
+	Short: "Ask for each unhealthy condition type whether it is fine, and add the answers as rules to a file. Use it with 'all --rules rules.yaml'.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := checkconditions.RunLearn(context.Background(), &arguments, args[0], learnOptions, os.Stdin, os.Stdout)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	learnCmd.Flags().BoolVar(&learnOptions.AcceptAll, "accept-all", false, "Don't ask, answer --accept-as for all unhealthy condition types. Use -n and --resource to limit it.")
+	learnCmd.Flags().StringVar(&learnOptions.AcceptAs, "accept-as", checkconditions.LearnIgnore, "The answer of --accept-all: healthy (the current status is the healthy one), skip (the condition type does not matter) or ignore (the current reasons are fine).")
+	learnCmd.Flags().StringSliceVar(&learnOptions.Resources, "resource", nil, "Only learn from these resource types (glob patterns like 'widgets' or '*.example.com'). Can be repeated.")
+	rootCmd.AddCommand(learnCmd)
+}
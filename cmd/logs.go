@@ -8,7 +8,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/guettli/check-conditions/pkg/checkconditions"
 	"github.com/spf13/cobra"
@@ -28,18 +27,11 @@ func init() {
 }
 
 func runLogs(args checkconditions.Arguments) {
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	configOverrides := &clientcmd.ConfigOverrides{}
-	kubeconfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
-
-	config, err := kubeconfig.ClientConfig()
+	config, err := args.RestConfig()
 	if err != nil {
 		panic(err.Error())
 	}
 
-	config.QPS = 1000
-	config.Burst = 1000
-
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		panic(err.Error())
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/guettli/check-conditions/pkg/checkconditions"
@@ -31,6 +33,22 @@ Output is usualy:
 		if arguments.RetryCount == 0 {
 			arguments.RetryForEver = true
 		}
+		var err error
+		if arguments.MinSeverity, err = checkconditions.ParseSeverity(minSeverity); err != nil {
+			return fmt.Errorf("--min-severity: %w", err)
+		}
+		if arguments.FailOn, err = checkconditions.ParseSeverity(failOn); err != nil {
+			return fmt.Errorf("--fail-on: %w", err)
+		}
+		// test-rules reports an invalid rules file as a failed test.
+		if rulesFile != "" && cmd != testRulesCmd {
+			file, err := checkconditions.LoadRulesFile(rulesFile)
+			if err != nil {
+				return err
+			}
+			arguments.Rules = file.Rules
+			arguments.ConditionRules = file.Conditions
+		}
 		return nil
 	},
 }
@@ -45,6 +63,12 @@ func Execute() {
 
 var arguments = checkconditions.Arguments{}
 
+var (
+	rulesFile   string
+	minSeverity string
+	failOn      string
+)
+
 func init() {
 	arguments.ProgrammStartTime = time.Now()
 	rootCmd.Long = "check-conditions " + buildVersion() + "\n\n" + rootCmd.Long
@@ -64,5 +88,55 @@ func init() {
 
 	rootCmd.PersistentFlags().Int16VarP(&arguments.RetryCount, "retry-count", "", 5, "Network errors: How many times to retry the command before giving up. This applies only to the first connection. As soon as a successful connection is made, the command will retry forever. Set to zero to also retry the first connection forever.")
 
+	rootCmd.PersistentFlags().IntVar(&arguments.Workers, "workers", 10, "Number of resource types which are listed concurrently.")
+
+	rootCmd.PersistentFlags().Float32Var(&arguments.QPS, "qps", 1000, "Client-side rate limit: queries per second to the API server.")
+
+	rootCmd.PersistentFlags().IntVar(&arguments.Burst, "burst", 1000, "Client-side rate limit: burst of queries to the API server.")
+
+	rootCmd.PersistentFlags().BoolVar(&arguments.Protobuf, "protobuf", false, "List the built-in types (pods, nodes, deployments, ...) with protobuf instead of JSON. Faster for big clusters. Custom resources are always listed with JSON.")
+
+	rootCmd.PersistentFlags().BoolVar(&arguments.Adaptive, "adaptive", false, "Halve the number of concurrent LIST requests when the API server answers with 429 Too Many Requests (for example because of API Priority and Fairness), and increase it slowly again.")
+
+	rootCmd.PersistentFlags().BoolVar(&arguments.Stats, "stats", false, "Print the LIST duration and the number of objects per resource type after the summary.")
+
+	rootCmd.PersistentFlags().BoolVar(&arguments.Explain, "explain", false, "Print the decision trail: which step (skip list, meaning maps, suffixes, ignore regexes, conditionDone, Ready-dedup, merge) decided about each condition. The summary shows how often each step decided.")
+
+	rootCmd.PersistentFlags().IntVar(&arguments.ListRetries, "list-retries", 3, "How often a LIST request of a resource type is retried after a transient error (timeout, 429, 5xx, network error). Exponential backoff, Retry-After is honored. The other resource types are checked meanwhile.")
+
+	rootCmd.PersistentFlags().DurationVar(&arguments.ListTimeout, "list-timeout", time.Minute, "Timeout of a single LIST request. Set to 0 to disable.")
+
 	rootCmd.PersistentFlags().DurationVar(&arguments.WarnDeletionTimestampOlderThan, "warn-deletion-older-than", 10*time.Minute, "Warn about resources whose deletionTimestamp is older than this duration. Set to 0 to disable.")
+
+	rootCmd.PersistentFlags().DurationVar(&arguments.StaleAfter, "stale-after", 0, "Report objects whose status.observedGeneration (or the observedGeneration of a condition) is lower than metadata.generation for longer than this duration, for example 10m. 0 (default) disables it.")
+
+	rootCmd.PersistentFlags().DurationVar(&arguments.UnknownGrace, "unknown-grace", 0, "Hide conditions with status Unknown whose lastTransitionTime is younger than this duration. They are counted in the summary. 0 (default) disables it. Ignored by wait.")
+
+	rootCmd.PersistentFlags().IntVar(&arguments.FlapThreshold, "flap-threshold", 0, "forever and while: Report conditions which changed their status at least this often within --flap-window, even if they are healthy right now, for example 4. 0 (default) disables it.")
+
+	rootCmd.PersistentFlags().DurationVar(&arguments.FlapWindow, "flap-window", 10*time.Minute, "forever and while: The sliding window for --flap-threshold.")
+
+	rootCmd.PersistentFlags().StringVar(&arguments.GroupBy, "group-by", "", "Group the output. 'owner' shows the findings as a tree below the top-most owner (via ownerReferences).")
+
+	rootCmd.PersistentFlags().StringVar(&arguments.Graph, "graph", "", "Write a graph of the objects with findings and their owners instead of lines. Supported: "+strings.Join(checkconditions.GraphFormats, ", ")+". Example: all --graph dot | dot -Tsvg > findings.svg")
+
+	rootCmd.PersistentFlags().DurationVar(&arguments.DiscoveryCacheTTL, "discovery-cache-ttl", 10*time.Minute, "Cache the discovery result (the list of api-resources) per cluster in $XDG_CACHE_HOME/check-conditions for this duration. Set to 0 to disable.")
+
+	rootCmd.PersistentFlags().BoolVar(&arguments.Priority, "priority", false, "Check resource types which had findings in previous runs first and print their findings immediately. The state is stored per cluster in $XDG_CACHE_HOME/check-conditions.")
+
+	rootCmd.PersistentFlags().BoolVar(&arguments.Stream, "stream", false, "Print the findings of each resource type as soon as it was checked (sorted per type), instead of all findings sorted at the end. The summary comes last.")
+
+	rootCmd.PersistentFlags().BoolVar(&arguments.Progress, "progress", false, "Show the number of checked resource types on stderr while a scan is running.")
+
+	rootCmd.PersistentFlags().BoolVar(&arguments.NamespaceAnnotations, "namespace-annotations", false, "Apply the check-conditions.guettli.de/* annotations of a namespace to all objects in the namespace. Needs permission to list namespaces.")
+
+	rootCmd.PersistentFlags().StringVar(&arguments.SilencesFile, "silences", "", "YAML file with silences which hide matching findings for some time. Managed with the 'silence' sub-command.")
+
+	rootCmd.PersistentFlags().BoolVar(&arguments.LintConditions, "lint-conditions", false, "Report conditions which violate the Kubernetes API conventions: missing fields, status other than True/False/Unknown, duplicate types, timestamps which are not RFC3339, reasons which are not CamelCase.")
+
+	rootCmd.PersistentFlags().StringVar(&rulesFile, "rules", "", "YAML file with rules which assign severities to findings and classify conditions (see the learn sub-command). They are checked before the builtin rules.")
+
+	rootCmd.PersistentFlags().StringVar(&minSeverity, "min-severity", "info", "Hide findings with a lower severity. Supported: "+strings.Join(checkconditions.Severities, ", "))
+
+	rootCmd.PersistentFlags().StringVar(&failOn, "fail-on", "info", "Lowest severity which makes 'all' exit with 1. Supported: "+strings.Join(checkconditions.Severities, ", "))
 }
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/guettli/check-conditions/pkg/checkconditions"
+	"github.com/spf13/cobra"
+)
+
+var (
+	silenceToAdd   checkconditions.Silence
+	silenceWindow  checkconditions.SilenceWindow
+	silenceExpires string
+)
+
+var silenceCmd = &cobra.Command{
+	Use:   "silence",
+	Short: "Manage the silences file given via --silences",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := rootCmd.PersistentPreRunE(cmd, args); err != nil {
+			return err
+		}
+		if arguments.SilencesFile == "" {
+			return fmt.Errorf("--silences is required")
+		}
+		return nil
+	},
+}
+
+var silenceAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a silence. It needs --comment and --expires or a window (--window-start and --window-end).",
+	Example: `  check-conditions silence add --silences silences.yaml -n 'team-*' --resource machinedeployments \
+    --type Ready --reason 'WaitingFor.*' --comment "upgrade, see ticket 123" --expires 4h
+  check-conditions silence add --silences silences.yaml --comment "maintenance window" \
+    --window-days Sat --window-start 22:00 --window-end 04:00 --window-timezone Europe/Berlin`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		now := time.Now()
+		s := silenceToAdd
+		if s.Author == "" {
+			s.Author = os.Getenv("USER")
+		}
+		if s.Author == "" {
+			fmt.Println("--author is required, $USER is not set")
+			os.Exit(3)
+		}
+		if silenceExpires != "" {
+			expiresAt, err := parseExpires(silenceExpires, now)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(3)
+			}
+			s.ExpiresAt = &expiresAt
+		}
+		if silenceWindow.Start != "" || silenceWindow.End != "" {
+			window := silenceWindow
+			s.Window = &window
+		}
+		switch len(arguments.NamespacePatterns) {
+		case 0:
+		case 1:
+			s.Namespace = arguments.NamespacePatterns[0]
+		default:
+			fmt.Println("a silence takes a single -n pattern")
+			os.Exit(3)
+		}
+		s, err := checkconditions.AddSilence(arguments.SilencesFile, s, now)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		fmt.Printf("Added silence %s to %s\n", s.ID, arguments.SilencesFile)
+	},
+}
+
+var silenceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all silences and whether they are active, inactive (outside of their window) or expired",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		silences, err := checkconditions.LoadSilences(arguments.SilencesFile)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		checkconditions.WriteSilences(os.Stdout, silences, time.Now())
+	},
+}
+
+var silenceExpireCmd = &cobra.Command{
+	Use:   "expire id...",
+	Short: "Expire silences now",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, id := range args {
+			if err := checkconditions.ExpireSilence(arguments.SilencesFile, id, time.Now()); err != nil {
+				fmt.Println(err)
+				os.Exit(3)
+			}
+			fmt.Printf("Expired silence %s\n", id)
+		}
+	},
+}
+
+// parseExpires accepts a duration relative to now ("4h") or a RFC3339 time.
+func parseExpires(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(d).UTC().Truncate(time.Second), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid --expires %q: expected a duration like 4h or a RFC3339 time", s)
+	}
+	return t, nil
+}
+
+func init() {
+	f := silenceAddCmd.Flags()
+	f.StringVar(&silenceToAdd.Resource, "resource", "", "Glob pattern for the resource, for example machines")
+	f.StringVar(&silenceToAdd.Name, "object-name", "", "Glob pattern for the name of the object")
+	f.StringVar(&silenceToAdd.Type, "type", "", "Glob pattern for the condition type, for example Ready")
+	f.StringVar(&silenceToAdd.Reason, "reason", "", "Regex for the condition reason")
+	f.StringVar(&silenceToAdd.Author, "author", "", "Author of the silence. Default: $USER")
+	f.StringVar(&silenceToAdd.Comment, "comment", "", "Why the findings are silenced")
+	f.StringVar(&silenceExpires, "expires", "", "Duration (4h) or RFC3339 time when the silence ends")
+	f.StringSliceVar(&silenceWindow.Days, "window-days", nil, "Days on which the window starts, for example Sat,Sun. Default: every day")
+	f.StringVar(&silenceWindow.Start, "window-start", "", "Start of a recurring window, for example 22:00")
+	f.StringVar(&silenceWindow.End, "window-end", "", "End of a recurring window, for example 04:00")
+	f.StringVar(&silenceWindow.Timezone, "window-timezone", "", "Timezone of the window, for example Europe/Berlin. Default: local time")
+
+	silenceCmd.AddCommand(silenceAddCmd, silenceListCmd, silenceExpireCmd)
+	rootCmd.AddCommand(silenceCmd)
+}
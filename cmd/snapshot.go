@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/guettli/check-conditions/pkg/checkconditions"
+	"github.com/spf13/cobra"
+)
+
+var snapshotOnlyWithConditions bool
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot file.tar.gz",
+	Short: "Write all objects of all api-resources to a compressed archive. Check it later with 'all --from-snapshot file.tar.gz'.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := checkconditions.RunSnapshot(context.Background(), &arguments, args[0], snapshotOnlyWithConditions)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	snapshotCmd.Flags().BoolVar(&snapshotOnlyWithConditions, "only-with-conditions", false, "Only write objects which have conditions or a deletionTimestamp. Makes the archive much smaller.")
+	rootCmd.AddCommand(snapshotCmd)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/guettli/check-conditions/pkg/checkconditions"
+	"github.com/spf13/cobra"
+)
+
+var testRulesCmd = &cobra.Command{
+	Use:   "test-rules fixture-file-or-directory...",
+	Short: "Classify the conditions of fixture objects with the builtin rules and --rules, and report the conditions whose verdict is not the expected one. Exit code 1 on mismatches or an invalid rules file.",
+	Example: `  check-conditions test-rules pkg/checkconditions/testdata/rules
+  check-conditions test-rules --rules rules.yaml fixtures/`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed, err := checkconditions.RunTestRules(&arguments, rulesFile, args, os.Stdout)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		if failed {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(testRulesCmd)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/guettli/check-conditions/pkg/checkconditions"
+	"github.com/spf13/cobra"
+)
+
+var waitFiles []string
+
+var waitCmd = &cobra.Command{
+	Use:   "wait -f manifest.yaml",
+	Short: "Wait until the objects of a manifest and all objects they own (via ownerReferences) are healthy. Exit code 2 if --timeout is reached.",
+	Example: `  kubectl apply -f app.yaml
+  check-conditions wait -f app.yaml --timeout 5m
+  helm template my-chart | check-conditions wait -f -`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := checkconditions.RunWait(context.Background(), &arguments, waitFiles, os.Stdin)
+		if errors.Is(err, checkconditions.ErrTimeout) {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		os.Exit(0)
+	},
+}
+
+func init() {
+	waitCmd.Flags().StringSliceVarP(&waitFiles, "filename", "f", nil, "Manifest files or directories (YAML/JSON) with the objects to wait for. '-' reads stdin.")
+	_ = waitCmd.MarkFlagRequired("filename")
+	rootCmd.AddCommand(waitCmd)
+}
@@ -0,0 +1,196 @@
+package checkconditions
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Annotations which opt an object (or all objects of a namespace, see
+// Arguments.NamespaceAnnotations) out of checks.
+const (
+	// AnnotationIgnore is a comma-separated list of condition types (glob
+	// patterns are supported) which are not reported, for example
+	// "Ready,Synced". Findings which are not about a condition can be
+	// ignored by their type, for example "DeletionTimestamp".
+	AnnotationIgnore = "check-conditions.guettli.de/ignore"
+	// AnnotationIgnoreAll "true" ignores all findings of the object.
+	AnnotationIgnoreAll = "check-conditions.guettli.de/ignore-all"
+	// AnnotationGrace is a duration like "10m". Findings are ignored until
+	// the condition (or the deletionTimestamp) is older than this.
+	AnnotationGrace = "check-conditions.guettli.de/grace"
+)
+
+// invalidAnnotationType is the type of findings about annotations which
+// can't be parsed.
+const invalidAnnotationType = "InvalidAnnotation"
+
+// objectOptOut is the parsed content of the annotations of an object and
+// its namespace.
+type objectOptOut struct {
+	all   bool
+	types []string
+	grace time.Duration
+	// invalid describes annotations which could not be parsed.
+	invalid []string
+}
+
+// optOutOf parses the annotations of the namespace of the object, then the
+// annotations of the object. The annotations of the object override those
+// of the namespace. Invalid annotations of the namespace are reported once,
+// see invalidNamespaceAnnotations.
+func (a *Arguments) optOutOf(obj unstructured.Unstructured) objectOptOut {
+	var o objectOptOut
+	if ns := obj.GetNamespace(); ns != "" {
+		o.parse(a.namespaceAnnotations[ns])
+		o.invalid = nil
+	}
+	o.parse(obj.GetAnnotations())
+	return o
+}
+
+func (o *objectOptOut) parse(annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+	invalid := func(key, value string, err error) {
+		o.invalid = append(o.invalid, fmt.Sprintf("%s %q: %v", key, value, err))
+	}
+	if v, ok := annotations[AnnotationIgnoreAll]; ok {
+		all, err := strconv.ParseBool(v)
+		if err != nil {
+			invalid(AnnotationIgnoreAll, v, err)
+		} else {
+			o.all = all
+		}
+	}
+	if v, ok := annotations[AnnotationIgnore]; ok {
+		o.types = nil
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				o.types = append(o.types, t)
+			}
+		}
+		if err := validatePatterns(o.types); err != nil {
+			invalid(AnnotationIgnore, v, err)
+			o.types = nil
+		}
+	}
+	if v, ok := annotations[AnnotationGrace]; ok {
+		grace, err := time.ParseDuration(v)
+		if err != nil {
+			invalid(AnnotationGrace, v, err)
+		} else {
+			o.grace = grace
+		}
+	}
+}
+
+// ignores reports whether a finding is ignored. A merged finding is only
+// ignored if all of its types are ignored. since is the lastTransitionTime
+// of the condition, zero if unknown.
+func (o objectOptOut) ignores(types []string, since time.Time, now time.Time) bool {
+	if o.all {
+		return true
+	}
+	if o.grace > 0 && !since.IsZero() && now.Sub(since) < o.grace {
+		return true
+	}
+	if len(o.types) == 0 {
+		return false
+	}
+	for _, t := range types {
+		if !matchAnyPattern(t, o.types) {
+			return false
+		}
+	}
+	return true
+}
+
+// namespacesGVR is the resource type of namespaces.
+var namespacesGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// invalidNamespaceAnnotations returns one finding per invalid annotation of
+// the namespaces in scope, see Arguments.NamespaceAnnotations. The findings
+// are about the namespace, not about each of its objects.
+func invalidNamespaceAnnotations(args *Arguments) handleResourceTypeOutput {
+	var output handleResourceTypeOutput
+	namespaces := make([]string, 0, len(args.namespaceAnnotations))
+	for ns := range args.namespaceAnnotations {
+		if args.namespaceFilterActive() && !matchAnyPattern(ns, args.NamespacePatterns) ||
+			matchAnyPattern(ns, args.ExcludeNamespacePatterns) {
+			continue
+		}
+		namespaces = append(namespaces, ns)
+	}
+	slices.Sort(namespaces)
+	for _, ns := range namespaces {
+		var o objectOptOut
+		o.parse(args.namespaceAnnotations[ns])
+		for _, msg := range o.invalid {
+			f := Finding{GVR: namespacesGVR, Name: ns, Type: invalidAnnotationType, Text: "Invalid annotation " + msg}
+			if !args.keepFinding(&f, o, &output) {
+				continue
+			}
+			if args.WhileRegex != nil {
+				output.whileRegexDidMatch = true
+			}
+			output.findings = append(output.findings, f)
+		}
+	}
+	return output
+}
+
+// reportsNamespaceAnnotations reports whether the invalid annotations of a
+// namespace object are reported by invalidNamespaceAnnotations already.
+func (a *Arguments) reportsNamespaceAnnotations(gvr schema.GroupVersionResource, obj unstructured.Unstructured) bool {
+	if gvr.Group != "" || gvr.Resource != "namespaces" {
+		return false
+	}
+	_, ok := a.namespaceAnnotations[obj.GetName()]
+	return ok
+}
+
+// loadNamespaceAnnotations reads the annotations of all namespaces, see
+// Arguments.NamespaceAnnotations. If listing namespaces is not allowed, only
+// the annotations of the objects are used.
+func loadNamespaceAnnotations(ctx context.Context, clientset kubernetes.Interface) (map[string]map[string]string, error) {
+	nsList, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsForbidden(err) {
+			fmt.Fprintf(os.Stderr, "Listing namespaces is not allowed, namespace annotations are not used.\n")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing namespaces: %w", err)
+	}
+	annotations := make(map[string]map[string]string, len(nsList.Items))
+	for i := range nsList.Items {
+		annotations[nsList.Items[i].Name] = nsList.Items[i].Annotations
+	}
+	return annotations, nil
+}
+
+// namespaceAnnotationsOf collects the annotations of namespace objects which
+// were read from files or a snapshot.
+func namespaceAnnotationsOf(lists []resourceList) map[string]map[string]string {
+	annotations := map[string]map[string]string{}
+	for _, rl := range lists {
+		if rl.gvr.Group != "" || rl.gvr.Resource != "namespaces" {
+			continue
+		}
+		for _, obj := range rl.list.Items {
+			annotations[obj.GetName()] = obj.GetAnnotations()
+		}
+	}
+	return annotations
+}
@@ -0,0 +1,170 @@
+package checkconditions
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func annotatedPod(name string, annotations map[string]string, conditions ...map[string]interface{}) unstructured.Unstructured {
+	obj := newTestObject(podsGVR, "Pod", "default", name, conditions...)
+	obj.SetAnnotations(annotations)
+	return *obj
+}
+
+func TestAnnotations(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	synced := map[string]interface{}{"type": "Synced", "status": "False", "reason": "Testing"}
+	recent := readyCondition("False")
+	recent["lastTransitionTime"] = now.Add(-5 * time.Minute).Format(time.RFC3339)
+	old := readyCondition("False")
+	old["lastTransitionTime"] = now.Add(-time.Hour).Format(time.RFC3339)
+
+	objects := []unstructured.Unstructured{
+		annotatedPod("ignore-types", map[string]string{AnnotationIgnore: "Ready, Sync*"}, readyCondition("False"), synced),
+		annotatedPod("ignore-one", map[string]string{AnnotationIgnore: "Synced"}, readyCondition("False"), synced),
+		annotatedPod("ignore-all", map[string]string{AnnotationIgnoreAll: "true"}, readyCondition("False")),
+		annotatedPod("grace-recent", map[string]string{AnnotationGrace: "10m"}, recent),
+		annotatedPod("grace-old", map[string]string{AnnotationGrace: "10m"}, old),
+		annotatedPod("invalid", map[string]string{AnnotationGrace: "soon"}, readyCondition("True")),
+	}
+	args := &Arguments{Now: now}
+	counter, err := checkResourceLists(args, groupByResource(objects))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"ignore-types", "ignore-all", "grace-recent"} {
+		if got := linesContaining(counter.Lines, " "+name+" "); len(got) != 0 {
+			t.Errorf("expected %s to be ignored, got %v", name, got)
+		}
+	}
+	if got := linesContaining(counter.Lines, " ignore-one "); len(got) != 1 || !strings.Contains(got[0], "Ready=False") {
+		t.Errorf("expected only Ready of ignore-one, got %v", got)
+	}
+	if got := linesContaining(counter.Lines, " grace-old "); len(got) != 1 {
+		t.Errorf("expected grace-old after the grace period, got %v", got)
+	}
+	if got := linesContaining(counter.Lines, " invalid "); len(got) != 1 || !strings.Contains(got[0], "Invalid annotation "+AnnotationGrace) {
+		t.Errorf("expected invalid annotation finding, got %v", got)
+	}
+	if counter.IgnoredFindings != 5 {
+		t.Errorf("expected 5 ignored findings, got %d", counter.IgnoredFindings)
+	}
+
+	// Findings about invalid annotations have a severity like other findings.
+	args = &Arguments{Now: now, MinSeverity: SeverityError}
+	counter, err = checkResourceLists(args, groupByResource(objects))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := linesContaining(counter.Lines, "Invalid annotation"); len(got) != 0 {
+		t.Errorf("expected the invalid annotation finding to be below --min-severity, got %v", got)
+	}
+	args = &Arguments{Now: now, FailOn: SeverityError}
+	counter, err = checkResourceLists(args, groupByResource(objects[5:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.Findings) != 1 || counter.Findings[0].Severity != SeverityWarning || counter.unhealthy(args) {
+		t.Errorf("expected a warning which does not fail with --fail-on error, got %+v", counter.Findings)
+	}
+}
+
+func TestNamespaceAnnotations(t *testing.T) {
+	cluster := defaultTestCluster()
+	ctx := context.Background()
+	ns, err := cluster.clients.Kubernetes.CoreV1().Namespaces().Get(ctx, "team-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns.Annotations = map[string]string{AnnotationIgnoreAll: "true"}
+	if _, err := cluster.clients.Kubernetes.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	args := &Arguments{}
+	counter, err := RunAndGetCounterWithClients(ctx, cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := linesContaining(counter.Lines, "team-a "); len(got) != 2 {
+		t.Fatalf("expected namespace annotations to be off by default, got %v", counter.Lines)
+	}
+
+	args.NamespaceAnnotations = true
+	counter, err = RunAndGetCounterWithClients(ctx, cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := linesContaining(counter.Lines, "team-a "); len(got) != 0 {
+		t.Errorf("expected team-a to be ignored, got %v", got)
+	}
+	if len(counter.Lines) != 2 || counter.IgnoredFindings != 2 {
+		t.Errorf("expected 2 lines and 2 ignored findings, got %d: %v", counter.IgnoredFindings, counter.Lines)
+	}
+
+	// An annotation of the object overrides the namespace.
+	nsObject := unstructured.Unstructured{}
+	nsObject.SetAPIVersion("v1")
+	nsObject.SetKind("Namespace")
+	nsObject.SetName("default")
+	nsObject.SetAnnotations(map[string]string{AnnotationIgnoreAll: "true"})
+	pod := annotatedPod("not-ignored", map[string]string{AnnotationIgnoreAll: "false"}, readyCondition("False"))
+	offline := &Arguments{NamespaceAnnotations: true}
+	counter, err = checkResourceLists(offline, groupByResource([]unstructured.Unstructured{nsObject, pod}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.Lines) != 1 || counter.IgnoredFindings != 0 {
+		t.Errorf("expected the pod finding, got %v", counter.Lines)
+	}
+}
+
+func TestInvalidNamespaceAnnotations(t *testing.T) {
+	cluster := defaultTestCluster()
+	ctx := context.Background()
+	ns, err := cluster.clients.Kubernetes.CoreV1().Namespaces().Get(ctx, "team-a", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns.Annotations = map[string]string{AnnotationGrace: "soon"}
+	if _, err := cluster.clients.Kubernetes.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// team-a has a pod and a widget, the annotation is reported once.
+	counter, err := RunAndGetCounterWithClients(ctx, cluster.clients, &Arguments{NamespaceAnnotations: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := linesContaining(counter.Lines, "Invalid annotation")
+	if len(got) != 1 || !strings.Contains(got[0], " namespaces team-a Invalid annotation "+AnnotationGrace) {
+		t.Fatalf("expected one finding about the namespace, got %v", got)
+	}
+
+	// Offline the namespace object is checked, too. It reports its
+	// annotations only once.
+	nsObject := unstructured.Unstructured{}
+	nsObject.SetAPIVersion("v1")
+	nsObject.SetKind("Namespace")
+	nsObject.SetName("default")
+	nsObject.SetAnnotations(map[string]string{AnnotationGrace: "soon"})
+	objects := []unstructured.Unstructured{nsObject, annotatedPod("pod-a", nil, readyCondition("True")), annotatedPod("pod-b", nil, readyCondition("True"))}
+	counter, err = checkResourceLists(&Arguments{NamespaceAnnotations: true}, groupByResource(objects))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := linesContaining(counter.Lines, "Invalid annotation"); len(got) != 1 {
+		t.Errorf("expected one finding about the namespace, got %v", counter.Lines)
+	}
+	counter, err = checkResourceLists(&Arguments{NamespaceAnnotations: true, NamespacePatterns: []string{"default"}}, groupByResource(objects))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := linesContaining(counter.Lines, "Invalid annotation"); len(got) != 1 {
+		t.Errorf("expected one finding about the namespace with -n, got %v", counter.Lines)
+	}
+}
@@ -0,0 +1,168 @@
+package checkconditions
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"sigs.k8s.io/yaml"
+)
+
+// BaselineEntry identifies a finding without the parts which change over
+// time, like durations.
+type BaselineEntry struct {
+	Namespace string `json:"namespace,omitempty"`
+	// Resource is resource.group, for example "machines.cluster.x-k8s.io".
+	Resource string `json:"resource"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func baselineEntryOf(f Finding) BaselineEntry {
+	return BaselineEntry{
+		Namespace: f.Namespace,
+		Resource:  f.GVR.GroupResource().String(),
+		Name:      f.Name,
+		Type:      f.Type,
+		Reason:    f.Reason,
+	}
+}
+
+func (e BaselineEntry) String() string {
+	return strings.TrimRight(fmt.Sprintf("  %s %s %s %s %s", e.Namespace, e.Resource, e.Name, e.Type, e.Reason), " ")
+}
+
+// Baseline is the content of the file written by --write-baseline.
+type Baseline struct {
+	CreatedAt time.Time       `json:"createdAt"`
+	Findings  []BaselineEntry `json:"findings"`
+}
+
+// newBaseline returns the sorted, unique entries of the findings.
+func newBaseline(findings []Finding, now time.Time) Baseline {
+	b := Baseline{CreatedAt: now.UTC().Truncate(time.Second)}
+	for _, f := range findings {
+		b.Findings = append(b.Findings, baselineEntryOf(f))
+	}
+	slices.SortFunc(b.Findings, func(x, y BaselineEntry) int {
+		return strings.Compare(x.String(), y.String())
+	})
+	b.Findings = slices.Compact(b.Findings)
+	return b
+}
+
+// LoadBaseline reads a file written by --write-baseline.
+func LoadBaseline(filename string) (*Baseline, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading baseline: %w", err)
+	}
+	var b Baseline
+	if err := yaml.UnmarshalStrict(data, &b); err != nil {
+		return nil, fmt.Errorf("error parsing baseline %s: %w", filename, err)
+	}
+	return &b, nil
+}
+
+// writeBaseline writes the baseline file atomically. Like SaveSilences, the
+// file keeps its mode and a new file gets 0644.
+func writeBaseline(filename string, b Baseline) error {
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(filename); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("error writing baseline: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing baseline: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing baseline: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing baseline: %w", err)
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// baselineDiff sorts the findings of a scan into new ones and those which
+// are in the baseline. Resolved are the entries of the baseline which were
+// not found any more.
+type baselineDiff struct {
+	new          []Finding
+	stillPresent []Finding
+	resolved     []BaselineEntry
+}
+
+func diffBaseline(b *Baseline, findings []Finding) baselineDiff {
+	var d baselineDiff
+	known := make(map[BaselineEntry]bool, len(b.Findings))
+	for _, e := range b.Findings {
+		known[e] = false
+	}
+	for _, f := range findings {
+		e := baselineEntryOf(f)
+		if _, ok := known[e]; ok {
+			known[e] = true
+			d.stillPresent = append(d.stillPresent, f)
+			continue
+		}
+		d.new = append(d.new, f)
+	}
+	for _, e := range b.Findings {
+		if !known[e] {
+			d.resolved = append(d.resolved, e)
+		}
+	}
+	return d
+}
+
+// unhealthy reports whether a new finding has at least the severity of
+// Arguments.FailOn.
+func (d baselineDiff) unhealthy(args *Arguments) bool {
+	c := Counter{Findings: d.new}
+	return c.unhealthy(args)
+}
+
+func (d baselineDiff) write(w io.Writer) {
+	fmt.Fprintf(w, "New findings (%d):\n", len(d.new))
+	for _, f := range d.new {
+		fmt.Fprintln(w, f.Line())
+	}
+	fmt.Fprintf(w, "Still present, in baseline (%d):\n", len(d.stillPresent))
+	for _, f := range d.stillPresent {
+		fmt.Fprintln(w, f.Line())
+	}
+	fmt.Fprintf(w, "Resolved, in baseline but gone (%d):\n", len(d.resolved))
+	for _, e := range d.resolved {
+		fmt.Fprintln(w, e.String())
+	}
+}
+
+// loadBaseline reads Arguments.BaselineFile.
+func (a *Arguments) loadBaseline() error {
+	a.baseline = nil
+	if a.BaselineFile == "" {
+		return nil
+	}
+	b, err := LoadBaseline(a.BaselineFile)
+	if err != nil {
+		return err
+	}
+	a.baseline = b
+	return nil
+}
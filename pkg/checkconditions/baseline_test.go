@@ -0,0 +1,108 @@
+package checkconditions
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBaseline(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "baseline.yaml")
+	cluster := defaultTestCluster()
+	ctx := context.Background()
+
+	args := &Arguments{WriteBaselineFile: filename}
+	counter, err := RunAndGetCounterWithClients(ctx, cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unhealthy, err := printCounter(args, &counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !unhealthy {
+		t.Fatal("expected unhealthy without baseline")
+	}
+	b, err := LoadBaseline(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := BaselineEntry{Namespace: "team-a", Resource: "widgets.example.com", Name: "widget-a", Type: "Ready", Reason: "Testing"}
+	if len(b.Findings) != 4 || b.Findings[2] != want {
+		t.Fatalf("unexpected baseline %+v", b.Findings)
+	}
+
+	// pod-a got healthy, a new pod is unhealthy.
+	if err := cluster.dynamic.Tracker().Update(podsGVR,
+		newTestObject(podsGVR, "Pod", "team-a", "pod-a", readyCondition("True")), "team-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.dynamic.Tracker().Create(podsGVR,
+		newTestObject(podsGVR, "Pod", "team-b", "pod-new", readyCondition("False")), "team-b"); err != nil {
+		t.Fatal(err)
+	}
+	args = &Arguments{BaselineFile: filename}
+	counter, err = RunAndGetCounterWithClients(ctx, cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := diffBaseline(args.baseline, counter.Findings)
+	if len(d.new) != 1 || d.new[0].Name != "pod-new" {
+		t.Errorf("unexpected new findings %v", d.new)
+	}
+	if len(d.stillPresent) != 3 {
+		t.Errorf("unexpected still present findings %v", d.stillPresent)
+	}
+	if len(d.resolved) != 1 || d.resolved[0].Name != "pod-a" {
+		t.Errorf("unexpected resolved findings %v", d.resolved)
+	}
+	if !d.unhealthy(args) {
+		t.Error("expected unhealthy because of the new finding")
+	}
+	var buf bytes.Buffer
+	d.write(&buf)
+	for _, s := range []string{"New findings (1):\n", "Still present, in baseline (3):\n", "Resolved, in baseline but gone (1):\n  team-a pods pod-a Ready Testing\n"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected %q in output:\n%s", s, buf.String())
+		}
+	}
+
+	// Durations are not part of the baseline.
+	f := Finding{GVR: podsGVR, Namespace: "ns", Name: "p", Type: "Ready", Reason: "Testing", Text: "(5m0s)"}
+	later := f
+	later.Text = "(10m0s)"
+	d = diffBaseline(&Baseline{Findings: newBaseline([]Finding{f}, time.Now()).Findings}, []Finding{later})
+	if len(d.new) != 0 || len(d.stillPresent) != 1 {
+		t.Errorf("expected the finding to be known, got %+v", d)
+	}
+}
+
+func TestWriteBaselineMode(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "baseline.yaml")
+	if err := writeBaseline(filename, Baseline{}); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o644 {
+		t.Errorf("new baseline file should have mode 0644, got %v", fi.Mode().Perm())
+	}
+	if err := os.Chmod(filename, 0o664); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBaseline(filename, Baseline{}); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err = os.Stat(filename); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o664 {
+		t.Errorf("baseline file should keep its mode 0664, got %v", fi.Mode().Perm())
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
+	"io"
+	"net/http"
 	"os"
 	"path"
 	"regexp"
@@ -12,11 +13,13 @@ import (
 	"sync"
 	"time"
 
+	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
@@ -46,7 +49,186 @@ type Arguments struct {
 	// WarnDeletionTimestampOlderThan warns about resources whose deletionTimestamp
 	// is older than this duration. Set to 0 to disable.
 	WarnDeletionTimestampOlderThan time.Duration
-	forbiddenResourcesPrinted      bool
+	// StaleAfter reports objects whose status or conditions were written
+	// for an older generation for longer than this duration. 0 disables it.
+	StaleAfter time.Duration
+	// UnknownGrace hides conditions with status Unknown whose
+	// lastTransitionTime is younger than this duration. Unknown is often a
+	// short phase, for example while a node restarts its kubelet. 0 disables
+	// it. RunWait ignores it.
+	UnknownGrace time.Duration
+	// ListRetries is how often a LIST request of a resource type is retried
+	// after a transient error, with exponential backoff. Other resource
+	// types are checked meanwhile.
+	ListRetries int
+	// ListTimeout is the timeout of a single LIST request. Zero means no
+	// timeout.
+	ListTimeout time.Duration
+	// Workers is the number of resource types which are listed
+	// concurrently. Zero means 10.
+	Workers int
+	// QPS and Burst configure the client-side rate limit. Zero means 1000.
+	QPS   float32
+	Burst int
+	// Protobuf lists the built-in types (pods, nodes, deployments, ...) with
+	// protobuf instead of JSON, which is faster for big clusters. Custom
+	// resources are always listed with JSON.
+	Protobuf bool
+	// Stream prints the findings of each resource type as soon as it was
+	// checked, instead of all findings sorted at the end.
+	Stream bool
+	// Progress writes the number of checked resource types to stderr while
+	// a scan is running.
+	Progress bool
+	// Adaptive reduces the concurrency when the API server answers with 429
+	// Too Many Requests, for example because of API Priority and Fairness.
+	Adaptive bool
+	// Stats prints the LIST duration and the number of objects per resource
+	// type after the summary.
+	Stats bool
+	// Explain prints the decision trail: which classification step decided
+	// about each condition. The summary shows how often each step decided.
+	Explain bool
+	// FlapThreshold reports conditions which changed at least this often
+	// within FlapWindow, even if they are healthy right now. Only "forever"
+	// and "while" detect flapping. 0 disables it.
+	FlapThreshold int
+	FlapWindow    time.Duration
+	// Priority checks resource types which had findings in previous runs
+	// first and prints their findings immediately. The state is stored per
+	// cluster in the user's cache directory.
+	Priority bool
+	// DiscoveryCacheTTL is how long the discovery result is cached on disk.
+	// Zero disables the cache.
+	DiscoveryCacheTTL time.Duration
+	// GroupBy is empty or GroupByOwner.
+	GroupBy string
+	// Graph is empty or one of GraphFormats. The output is a graph of the
+	// unhealthy objects and their owners instead of lines.
+	Graph string
+	// NamespaceAnnotations makes the annotations of a namespace (see
+	// AnnotationIgnore) apply to all objects in the namespace.
+	NamespaceAnnotations bool
+	// SilencesFile is read at the start of each scan, see Silence.
+	SilencesFile string
+	// BaselineFile was written by WriteBaselineFile. Only findings which
+	// are not in the baseline make the scan unhealthy.
+	BaselineFile string
+	// WriteBaselineFile records the findings of the scan, see Baseline.
+	WriteBaselineFile string
+	// Rules assign severities to findings. They are checked before the
+	// builtin rules, see LoadRules.
+	Rules []Rule
+	// ConditionRules decide whether a condition is healthy, unhealthy or
+	// skipped. They are checked before the builtin classification, see
+	// LoadRulesFile.
+	ConditionRules []ConditionRule
+	// MinSeverity hides findings with a lower severity.
+	MinSeverity Severity
+	// FailOn is the lowest severity which makes a scan unhealthy (exit code
+	// 1). Zero means SeverityInfo, so any finding.
+	FailOn Severity
+	// LintConditions reports conditions which violate the API conventions,
+	// for example a status which is not True, False or Unknown.
+	LintConditions bool
+	// Checkers are called for each object. Nil means DefaultCheckers.
+	Checkers *CheckerRegistry
+	// Clients overrides the clients created from the kubeconfig. Nil in
+	// normal use; tests set it to client-go fakes.
+	Clients *Clients
+	// Now is the reference time for durations and the deletionTimestamp age.
+	// Zero means the current time. Set when checking objects offline.
+	Now                       time.Time
+	forbiddenResourcesPrinted bool
+	// namespaceAnnotations are the annotations of the namespaces, loaded at
+	// the start of a scan if NamespaceAnnotations is set.
+	namespaceAnnotations map[string]map[string]string
+	silences             []Silence
+	baseline             *Baseline
+	// flapping is set by startFlapDetection.
+	flapping *flapTracker
+	// adaptiveLimiter is set by limiter.
+	adaptiveLimiter *adaptiveLimiter
+	// collectOwners makes the scan keep the ownerReferences of all objects,
+	// used by RunWait.
+	collectOwners bool
+	// keepLists makes the scan keep the listed objects in Counter, used
+	// for snapshots.
+	keepLists bool
+	// messageWriter gets the messages of a scan, for example about a lost
+	// connection. Nil means stdout, see messages.
+	messageWriter io.Writer
+}
+
+// messages returns where the messages of a scan are written. Sub-commands
+// whose stdout must stay parseable write them to stderr.
+func (a *Arguments) messages() io.Writer {
+	if a.messageWriter == nil {
+		return os.Stdout
+	}
+	return a.messageWriter
+}
+
+// rejectOutputFlags returns an error if flags are set which change the
+// output of a scan. Sub-commands which write their own output reject them.
+func (a *Arguments) rejectOutputFlags(command string) error {
+	if a.Priority || a.Stream || a.GroupBy != "" || a.Graph != "" || a.BaselineFile != "" || a.WriteBaselineFile != "" {
+		return fmt.Errorf("%s can't be combined with --priority, --stream, --group-by, --graph, --baseline or --write-baseline", command)
+	}
+	return nil
+}
+
+// validate checks the arguments which can't be checked by cobra.
+func (a *Arguments) validate() error {
+	if err := validatePatterns(a.ExcludeNamespacePatterns); err != nil {
+		return err
+	}
+	if a.GroupBy != "" && a.GroupBy != GroupByOwner {
+		return fmt.Errorf("invalid --group-by %q, supported: %s", a.GroupBy, GroupByOwner)
+	}
+	if a.Graph != "" && !slices.Contains(GraphFormats, a.Graph) {
+		return fmt.Errorf("invalid --graph %q, supported: %s", a.Graph, strings.Join(GraphFormats, ", "))
+	}
+	if a.BaselineFile != "" && (a.GroupBy != "" || a.Graph != "") {
+		return errors.New("--baseline can't be combined with --group-by or --graph")
+	}
+	if a.Stream && (a.GroupBy != "" || a.Graph != "" || a.BaselineFile != "") {
+		return errors.New("--stream can't be combined with --group-by, --graph or --baseline")
+	}
+	if a.Explain && a.Graph != "" {
+		return errors.New("--explain can't be combined with --graph")
+	}
+	if a.FlapThreshold > 0 && a.FlapWindow <= 0 {
+		return errors.New("--flap-threshold needs a positive --flap-window")
+	}
+	for i := range a.Rules {
+		if err := a.Rules[i].compile(); err != nil {
+			return fmt.Errorf("invalid rule %d: %w", i+1, err)
+		}
+	}
+	for i := range a.ConditionRules {
+		if err := a.ConditionRules[i].compile(); err != nil {
+			return fmt.Errorf("invalid condition rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// loadFiles reads the files which are given via Arguments at the start of
+// each scan, so that "forever" picks up changes.
+func (a *Arguments) loadFiles() error {
+	if err := a.loadSilences(); err != nil {
+		return err
+	}
+	return a.loadBaseline()
+}
+
+// now returns the reference time for durations.
+func (a *Arguments) now() time.Time {
+	if a.Now.IsZero() {
+		return time.Now()
+	}
+	return a.Now
 }
 
 // matchAnyPattern reports whether name matches any of the given glob patterns.
@@ -82,6 +264,26 @@ func (a *Arguments) namespaceSet() map[string]struct{} {
 	return m
 }
 
+// namespaceInScope returns a filter for the namespaces of listed objects.
+func (a *Arguments) namespaceInScope() func(ns string) bool {
+	// When the user supplied multiple include namespaces (or globs), we list
+	// resources cluster-wide and drop the ones not in the resolved set. A
+	// single include is already filtered server-side. Excludes always apply
+	// here for namespaced lists (cluster-scoped resources have no namespace).
+	var nsInclude map[string]struct{}
+	if len(a.Namespaces) > 1 {
+		nsInclude = a.namespaceSet()
+	}
+	return func(ns string) bool {
+		if nsInclude != nil {
+			if _, ok := nsInclude[ns]; !ok {
+				return false
+			}
+		}
+		return ns == "" || !matchAnyPattern(ns, a.ExcludeNamespacePatterns)
+	}
+}
+
 // namespaceFilterActive reports whether the user requested a namespace filter
 // (regardless of whether the patterns have been resolved yet).
 func (a *Arguments) namespaceFilterActive() bool {
@@ -96,7 +298,7 @@ func patternHasGlob(p string) bool {
 // namespace names. Patterns without glob characters are kept as-is. Patterns
 // with glob characters are matched against the namespaces that exist in the
 // cluster. Returns an error if no namespace matches.
-func resolveNamespacePatterns(ctx context.Context, clientset *kubernetes.Clientset, patterns []string) ([]string, error) {
+func resolveNamespacePatterns(ctx context.Context, clientset kubernetes.Interface, patterns []string) ([]string, error) {
 	if len(patterns) == 0 {
 		return nil, nil
 	}
@@ -191,34 +393,132 @@ type Counter struct {
 	CheckedResources     int32
 	CheckedConditions    int32
 	CheckedResourceTypes int32
-	StartTime            time.Time
-	WhileRegexDidMatch   bool
-	Lines                []string
-	ForbiddenResources   []string
+	// IgnoredFindings were dropped because of annotations, see
+	// AnnotationIgnore.
+	IgnoredFindings int32
+	// SilencedFindings were dropped because of a Silence.
+	SilencedFindings int32
+	// UnknownFindings are the Findings with status Unknown.
+	UnknownFindings int32
+	// UnknownInGrace were dropped because of Arguments.UnknownGrace.
+	UnknownInGrace     int32
+	StartTime          time.Time
+	WhileRegexDidMatch bool
+	Lines              []string
+	// Findings are the findings behind Lines, in the same order.
+	Findings           []Finding
+	ForbiddenResources []string
+	// ListErrors are the resource types which could not be listed, except
+	// 403 Forbidden. They make the scan unhealthy, see classifyListError.
+	ListErrors []Finding
+	// serverResources and lists are only set with Arguments.keepLists.
+	serverResources []*metav1.APIResourceList
+	lists           []resourceList
+	typeStats       []resourceTypeStat
+	// notFoundResourceTypes counts types from discovery which returned 404
+	// on LIST.
+	notFoundResourceTypes int
+	// objects is only set if Arguments.needsOwners.
+	objects []objectRef
+	// earlyLines were already printed while the scan was running, see
+	// Arguments.Priority. The value is the number of occurrences.
+	earlyLines map[string]int
+	// explanations and ruleHits are only set with Arguments.Explain.
+	explanations []string
+	ruleHits     map[string]int
+}
+
+// resourceTypeStat is the outcome of listing one resource type.
+type resourceTypeStat struct {
+	gvr          schema.GroupVersionResource
+	lines        int
+	objects      int
+	listDuration time.Duration
 }
 
 func (c *Counter) add(o handleResourceTypeOutput) {
 	c.CheckedResources += o.checkedResources
 	c.CheckedConditions += o.checkedConditions
 	c.CheckedResourceTypes += o.checkedResourceTypes
-	c.Lines = append(c.Lines, o.lines...)
+	c.IgnoredFindings += o.ignoredFindings
+	c.SilencedFindings += o.silencedFindings
+	c.UnknownInGrace += o.unknownInGrace
+	for _, f := range o.findings {
+		if f.Status == unknownStatus {
+			c.UnknownFindings++
+		}
+		c.Findings = append(c.Findings, f)
+		c.Lines = append(c.Lines, f.Line())
+	}
+	c.objects = append(c.objects, o.objects...)
+	c.explanations = append(c.explanations, o.explanations...)
+	for step, n := range o.ruleHits {
+		if c.ruleHits == nil {
+			c.ruleHits = map[string]int{}
+		}
+		c.ruleHits[step] += n
+	}
 	if o.forbiddenResource != "" {
 		c.ForbiddenResources = append(c.ForbiddenResources, o.forbiddenResource)
 	}
+	if o.notFound {
+		c.notFoundResourceTypes++
+	}
+	if o.listError != nil {
+		c.ListErrors = append(c.ListErrors, *o.listError)
+	}
 	if o.whileRegexDidMatch {
 		c.WhileRegexDidMatch = true
 	}
+	if o.list != nil {
+		c.lists = append(c.lists, resourceList{gvr: o.gvr, list: o.list})
+	}
+	if o.checkedResourceTypes > 0 {
+		c.typeStats = append(c.typeStats, resourceTypeStat{
+			gvr:          o.gvr,
+			lines:        len(o.findings),
+			objects:      o.listedObjects,
+			listDuration: o.listDuration,
+		})
+	}
+}
+
+// printEarly prints the lines of a resource type right away, sorted, and
+// remembers them, so that printCounter does not print them again. See
+// Arguments.printsEarly.
+func (c *Counter) printEarly(o handleResourceTypeOutput) {
+	if c.earlyLines == nil {
+		c.earlyLines = map[string]int{}
+	}
+	lines := make([]string, 0, len(o.findings))
+	for _, f := range o.findings {
+		lines = append(lines, f.Line())
+	}
+	slices.Sort(lines)
+	for _, line := range lines {
+		fmt.Println(line)
+		c.earlyLines[line]++
+	}
 }
 
 // RunAllOnce returns true if an unhealthy condition was found.
 func RunAllOnce(ctx context.Context, args *Arguments) (bool, error) {
+	clients, err := args.clients()
+	if err != nil {
+		return false, err
+	}
+	return RunCheckAllConditionsWithClients(ctx, clients, args)
+}
+
+// restConfig loads the kubeconfig like kubectl does.
+func restConfig() (*restclient.Config, error) {
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 	configOverrides := &clientcmd.ConfigOverrides{}
 	kubeconfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
 
 	config, err := kubeconfig.ClientConfig()
 	if err != nil {
-		return false, fmt.Errorf("error creating client config: %w", err)
+		return nil, fmt.Errorf("error creating client config: %w", err)
 	}
 
 	// 80 concurrent requests were served in roughly 200ms
@@ -227,10 +527,55 @@ func RunAllOnce(ctx context.Context, args *Arguments) (bool, error) {
 	// to wait for getting results from an api-server running at localhost
 	config.QPS = 1000
 	config.Burst = 1000
-	return RunCheckAllConditions(ctx, config, args)
+	return config, nil
+}
+
+// RestConfig loads the kubeconfig and applies QPS and Burst. It is used by
+// sub-commands which create their own clients, like "logs".
+func (a *Arguments) RestConfig() (*restclient.Config, error) {
+	config, err := restConfig()
+	if err != nil {
+		return nil, err
+	}
+	if a.QPS > 0 {
+		config.QPS = a.QPS
+	}
+	if a.Burst > 0 {
+		config.Burst = a.Burst
+	}
+	return config, nil
+}
+
+// clients returns args.Clients, or creates clients from the kubeconfig.
+func (a *Arguments) clients() (*Clients, error) {
+	if a.Clients != nil {
+		return a.Clients, nil
+	}
+	config, err := a.RestConfig()
+	if err != nil {
+		return nil, err
+	}
+	if l := a.limiter(); l != nil {
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &adaptiveTransport{limiter: l, next: rt}
+		})
+	}
+	clients, err := NewClients(config)
+	if err != nil {
+		return nil, err
+	}
+	if a.DiscoveryCacheTTL > 0 && clients.CacheKey != "" {
+		cached, err := newDiskCachedDiscovery(config, clients.CacheKey, a.DiscoveryCacheTTL)
+		if err != nil {
+			return nil, err
+		}
+		clients.Discovery = cached
+	}
+	return clients, nil
 }
 
 func RunForever(ctx context.Context, args *Arguments) error {
+	args.startFlapDetection()
 	for {
 		_, err := RunAllOnce(ctx, args)
 		if err != nil {
@@ -242,6 +587,7 @@ func RunForever(ctx context.Context, args *Arguments) error {
 }
 
 func RunWhileRegex(ctx context.Context, arguments *Arguments) error {
+	arguments.startFlapDetection()
 	for {
 		again, err := runWhileInner(ctx, arguments)
 		if err != nil {
@@ -280,51 +626,134 @@ func runWhileInner(ctx context.Context, arguments *Arguments) (bool, error) {
 	return true, nil
 }
 
+// retryDelay is the pause between two attempts after a network error.
+var retryDelay = 1 * time.Second
+
 // If arguments.WhileRegex, then return true if there was a matching unhealthy condition.
 // Otherwise return true if there was at least one unhealthy condition.
 func RunCheckAllConditions(ctx context.Context, config *restclient.Config, args *Arguments) (bool, error) {
+	clients, err := NewClients(config)
+	if err != nil {
+		return false, err
+	}
+	return RunCheckAllConditionsWithClients(ctx, clients, args)
+}
+
+// RunCheckAllConditionsWithClients is like RunCheckAllConditions, but uses
+// the given clients instead of creating them from a rest config.
+func RunCheckAllConditionsWithClients(ctx context.Context, clients *Clients, args *Arguments) (bool, error) {
+	counter, err := runWithRetries(ctx, clients, args)
+	if err != nil {
+		return false, err
+	}
+	return printCounter(args, &counter)
+}
+
+// runWithRetries runs RunAndGetCounterWithClients and retries on transient
+// errors, see Arguments.RetryCount and isTransient.
+func runWithRetries(ctx context.Context, clients *Clients, args *Arguments) (Counter, error) {
 	// Get the list of all API resources available
 	var err error
 	var counter Counter
 	var i int16
+	var lostSince time.Time
 	for {
 		if args.Timeout > 0 {
 			d := time.Since(args.ProgrammStartTime)
 			if d > args.Timeout {
 				d := d.Round(time.Second)
-				return false, fmt.Errorf("timeout reached after %s", d.String())
+				return counter, fmt.Errorf("%w after %s", ErrTimeout, d.String())
 			}
 		}
-		counter, err = RunAndGetCounter(ctx, config, args)
+		counter, err = RunAndGetCounterWithClients(ctx, clients, args)
 		if err == nil {
+			if !lostSince.IsZero() {
+				fmt.Fprintf(args.messages(), "Connection restored after %s.\n", time.Since(lostSince).Round(time.Second))
+			}
 			// Successful connection, from now on retry forever.
 			args.RetryForEver = true
 			break
 		}
-		var netError net.Error
-		if !errors.As(err, &netError) {
-			return false, err
+		if !isTransient(err) {
+			return counter, err
 		}
 		if args.RetryForEver {
-			if i%10 == 0 {
-				fmt.Printf("a network error occured. Will retry forever: %v\n",
-					err)
+			if lostSince.IsZero() {
+				lostSince = time.Now()
+				fmt.Fprintf(args.messages(), "Connection lost: %v. Will retry forever.\n", err)
+			} else if i%10 == 0 {
+				fmt.Fprintf(args.messages(), "Connection still lost after %s: %v\n", time.Since(lostSince).Round(time.Second), err)
 			}
 		} else {
 			if i > args.RetryCount {
-				return false, fmt.Errorf("network error: %w", err)
+				return counter, fmt.Errorf("network error: %w", err)
 			}
-			fmt.Printf("a network error occured. Will retry %d times: %v\n",
+			fmt.Fprintf(args.messages(), "a network error occured. Will retry %d times: %v\n",
 				args.RetryCount-i, err)
 		}
-		time.Sleep(1 * time.Second)
+		delay := retryDelay
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok {
+			delay = max(delay, time.Duration(seconds)*time.Second)
+		}
+		time.Sleep(delay)
 		i++
 		continue
 	}
+	return counter, nil
+}
 
-	for _, line := range counter.Lines {
-		fmt.Println(line)
+// printCounter prints the lines and the summary of a scan. It returns the
+// result of the scan: true if the while-regex matched, or (without
+// while-regex) if there was at least one unhealthy condition. With a
+// baseline only new findings count.
+func printCounter(args *Arguments, counter *Counter) (bool, error) {
+	result := counter.WhileRegexDidMatch
+	var diff baselineDiff
+	if args.baseline != nil {
+		diff = diffBaseline(args.baseline, counter.Findings)
 	}
+	if args.WhileRegex == nil {
+		// "all" command
+		if args.baseline != nil {
+			result = diff.unhealthy(args)
+		} else {
+			result = counter.unhealthy(args)
+		}
+		if len(counter.ListErrors) > 0 {
+			// The objects of these types were not checked.
+			result = true
+		}
+	}
+	if args.WriteBaselineFile != "" {
+		if err := writeBaseline(args.WriteBaselineFile, newBaseline(counter.Findings, args.now())); err != nil {
+			return result, err
+		}
+	}
+	if args.Graph != "" {
+		// Nothing else, so that the output can be piped to "dot".
+		writeGraph(os.Stdout, args.Graph, counter)
+		return result, nil
+	}
+
+	if args.Explain {
+		writeExplanations(os.Stdout, counter)
+	}
+	switch {
+	case args.baseline != nil:
+		diff.write(os.Stdout)
+	case args.GroupBy == GroupByOwner:
+		writeOwnerTree(os.Stdout, counter)
+	default:
+		early := maps.Clone(counter.earlyLines)
+		for _, line := range counter.Lines {
+			if early[line] > 0 {
+				early[line]--
+				continue
+			}
+			fmt.Println(line)
+		}
+	}
+	writeListErrors(os.Stdout, counter.ListErrors)
 	if len(counter.ForbiddenResources) > 0 && !args.forbiddenResourcesPrinted {
 		seen := map[string]struct{}{}
 		uniq := make([]string, 0, len(counter.ForbiddenResources))
@@ -352,34 +781,59 @@ func RunCheckAllConditions(ctx context.Context, config *restclient.Config, args
 	default:
 		scope = fmt.Sprintf(" in namespaces %s", strings.Join(args.Namespaces, ","))
 	}
-	fmt.Printf("Checked %d conditions of %d resources of %d types%s. Duration: %s%s\n",
-		counter.CheckedConditions, counter.CheckedResources, counter.CheckedResourceTypes, scope, time.Since(counter.StartTime).Round(time.Millisecond), name)
-
-	if args.WhileRegex == nil {
-		// "all" command
-		if len(counter.Lines) > 0 {
-			return true, nil
+	args.printExpiredSilences(os.Stdout)
+	ignored := ""
+	if counter.IgnoredFindings > 0 {
+		ignored = fmt.Sprintf(", %d findings ignored by annotations", counter.IgnoredFindings)
+	}
+	if counter.SilencedFindings > 0 {
+		ignored += fmt.Sprintf(", %d findings silenced", counter.SilencedFindings)
+	}
+	if counter.UnknownFindings > 0 || counter.UnknownInGrace > 0 {
+		ignored += fmt.Sprintf(", %d Unknown findings", counter.UnknownFindings)
+		if counter.UnknownInGrace > 0 {
+			ignored += fmt.Sprintf(" (+%d within --unknown-grace)", counter.UnknownInGrace)
 		}
-		return false, nil
 	}
-
-	return counter.WhileRegexDidMatch, nil
+	fmt.Printf("Checked %d conditions of %d resources of %d types%s%s. Duration: %s%s\n",
+		counter.CheckedConditions, counter.CheckedResources, counter.CheckedResourceTypes, scope, ignored, time.Since(counter.StartTime).Round(time.Millisecond), name)
+	if args.WriteBaselineFile != "" {
+		fmt.Printf("Wrote %d findings to baseline %s\n", len(counter.Findings), args.WriteBaselineFile)
+	}
+	if args.Explain {
+		writeRuleHits(os.Stdout, counter.ruleHits)
+	}
+	if args.Stats {
+		writeStats(os.Stdout, args, counter)
+	}
+	return result, nil
 }
 
 func RunAndGetCounter(ctx context.Context, config *restclient.Config, args *Arguments) (Counter, error) {
-	counter := Counter{StartTime: time.Now()}
-	clientset, err := kubernetes.NewForConfig(config)
+	clients, err := NewClients(config)
 	if err != nil {
-		return counter, fmt.Errorf("error creating clientset: %w", err)
+		return Counter{StartTime: time.Now()}, err
 	}
+	return RunAndGetCounterWithClients(ctx, clients, args)
+}
 
-	if err := validatePatterns(args.ExcludeNamespacePatterns); err != nil {
+// RunAndGetCounterWithClients runs one scan with the given clients and
+// returns the sorted result without printing it.
+func RunAndGetCounterWithClients(ctx context.Context, clients *Clients, args *Arguments) (Counter, error) {
+	counter := Counter{StartTime: time.Now()}
+	if err := args.validate(); err != nil {
 		return counter, err
 	}
+	if err := args.loadFiles(); err != nil {
+		return counter, err
+	}
+	if args.flapping != nil {
+		args.flapping.prune(args.now(), args.FlapWindow)
+	}
 	if args.namespaceFilterActive() {
 		// Resolve once per run; subsequent retries reuse the resolved list.
 		if len(args.Namespaces) == 0 {
-			resolved, err := resolveNamespacePatterns(ctx, clientset, args.NamespacePatterns)
+			resolved, err := resolveNamespacePatterns(ctx, clients.Kubernetes, args.NamespacePatterns)
 			if err != nil {
 				return counter, err
 			}
@@ -387,21 +841,26 @@ func RunAndGetCounter(ctx context.Context, config *restclient.Config, args *Argu
 		}
 	}
 
-	dynClient, err := dynamic.NewForConfig(config)
+	serverResources, err := clients.Discovery.ServerPreferredResources()
 	if err != nil {
-		return counter, fmt.Errorf("error creating dynamic client: %w", err)
+		var groupErr *discovery.ErrGroupDiscoveryFailed
+		if !errors.As(err, &groupErr) {
+			return counter, fmt.Errorf("error getting server preferred resources: %w", err)
+		}
+		// The other groups are checked. The broken ones make the result
+		// unhealthy like a resource type which can't be listed.
+		for _, f := range newDiscoveryErrorFindings(groupErr) {
+			f.Severity = args.severityOf(f, f.Type)
+			counter.ListErrors = append(counter.ListErrors, f)
+		}
 	}
 
-	discoveryClient := clientset.Discovery()
-
-	serverResources, err := discoveryClient.ServerPreferredResources()
-	if err != nil {
-		if discovery.IsGroupDiscoveryFailedError(err) {
-			fmt.Printf("WARNING: The Kubernetes server has an orphaned API service. Server reports: %s\n", err.Error())
-			fmt.Printf("WARNING: To fix this, kubectl delete apiservice <service-name>\n")
-		} else {
-			return counter, fmt.Errorf("error getting server preferred resources: %w", err)
+	if args.NamespaceAnnotations {
+		args.namespaceAnnotations, err = loadNamespaceAnnotations(ctx, clients.Kubernetes)
+		if err != nil {
+			return counter, err
 		}
+		counter.add(invalidNamespaceAnnotations(args))
 	}
 
 	jobs := make(chan handleResourceTypeInput)
@@ -412,28 +871,78 @@ func RunAndGetCounter(ctx context.Context, config *restclient.Config, args *Argu
 	// Without: 320ms
 	// With 10 or more workers: 190ms
 
-	createWorkers(ctx, &wg, jobs, results)
+	if args.keepLists {
+		counter.serverResources = serverResources
+	}
+	var cache *priorityCache
+	if args.Priority && clients.CacheKey != "" {
+		cache = loadPriorityCache(clients.CacheKey)
+	}
+	inputs := jobInputs(serverResources, args, clients, cache)
+	var prog *progress
+	if args.Progress {
+		prog = newProgress(os.Stderr, len(inputs))
+	}
+
+	args.limiter()
+	createWorkers(ctx, &wg, args.workers(), jobs, results)
 
 	var wgCounter sync.WaitGroup
 	wgCounter.Add(1)
 	go func() {
 		for result := range results {
+			if args.printsEarly(result) && len(result.findings) > 0 {
+				prog.clear()
+				counter.printEarly(result)
+			}
+			prog.update(result)
 			counter.add(result)
 		}
 		wgCounter.Done()
 	}()
 
-	createJobs(serverResources, jobs, args, dynClient)
+	for _, input := range inputs {
+		jobs <- input
+	}
 
 	close(jobs)
 	wg.Wait()
 	close(results)
 	wgCounter.Wait()
-	slices.Sort(counter.Lines)
+	prog.clear()
+	counter.sort()
+	if counter.CheckedResourceTypes == 0 && len(counter.ListErrors) > 0 && transientListErrors(counter.ListErrors) {
+		return counter, fmt.Errorf("%w, first error: %s", errAllListsFailed, counter.ListErrors[0].Text)
+	}
+	if counter.notFoundResourceTypes > 0 {
+		// Discovery is outdated. Don't use the cached result next time.
+		invalidateDiscoveryCache(clients.Discovery)
+	}
+	if cache != nil {
+		cache.update(counter.typeStats, time.Now())
+		if err := cache.save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write priority cache: %v\n", err)
+		}
+	}
 	return counter, nil
 }
 
-func createJobs(serverResources []*metav1.APIResourceList, jobs chan handleResourceTypeInput, args *Arguments, dynClient *dynamic.DynamicClient) {
+// jobInputs returns one job per resource type. With a priority cache, the
+// types which had findings recently come first.
+func jobInputs(serverResources []*metav1.APIResourceList, args *Arguments, clients *Clients, cache *priorityCache) []handleResourceTypeInput {
+	inputs := resourceTypeInputs(serverResources, args, clients)
+	if cache != nil {
+		inputs = cache.order(inputs, time.Now())
+	}
+	return inputs
+}
+
+func resourceTypeInputs(serverResources []*metav1.APIResourceList, args *Arguments, clients *Clients) []handleResourceTypeInput {
+	var protobuf *protobufLister
+	if args.Protobuf {
+		protobuf = clients.protobuf
+	}
+	var inputs []handleResourceTypeInput
 	for _, resourceList := range serverResources {
 		groupVersion, err := schema.ParseGroupVersion(resourceList.GroupVersion)
 		if err != nil {
@@ -445,28 +954,34 @@ func createJobs(serverResources []*metav1.APIResourceList, jobs chan handleResou
 			if args.namespaceFilterActive() && !namespaced {
 				continue
 			}
-			jobs <- handleResourceTypeInput{
+			inputs = append(inputs, handleResourceTypeInput{
 				args:      args,
-				dynClient: dynClient,
+				dynClient: clients.Dynamic,
+				protobuf:  protobuf,
 				gvr: schema.GroupVersionResource{
 					Group:    groupVersion.Group,
 					Version:  groupVersion.Version,
 					Resource: resourceList.APIResources[i].Name,
 				},
+				kind:       resourceList.APIResources[i].Kind,
 				namespaced: namespaced,
-			}
+			})
 		}
 	}
+	return inputs
 }
 
-func createWorkers(ctx context.Context, wg *sync.WaitGroup, jobs chan handleResourceTypeInput, results chan handleResourceTypeOutput) {
-	for i := 0; i < 10; i++ {
+func createWorkers(ctx context.Context, wg *sync.WaitGroup, workers int, jobs chan handleResourceTypeInput, results chan handleResourceTypeOutput) {
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func(workerID int32) {
 			defer wg.Done()
 			for input := range jobs {
 				input.workerID = workerID
-				results <- handleResourceType(ctx, input)
+				release := input.args.acquireSlot()
+				output := handleResourceType(ctx, input)
+				release()
+				results <- output
 			}
 		}(int32(i))
 	}
@@ -479,68 +994,211 @@ func containsSlash(s string) bool {
 // printResources returns true if the conditions should get checked again N seconds later.
 func printResources(args *Arguments, list *unstructured.UnstructuredList, gvr schema.GroupVersionResource,
 	counter *handleResourceTypeOutput, workerID int32,
-) (lines []string, again bool) {
-	// When the user supplied multiple include namespaces (or globs), we list
-	// resources cluster-wide and drop the ones not in the resolved set. A
-	// single include is already filtered server-side. Excludes always apply
-	// here for namespaced lists (cluster-scoped resources have no namespace).
-	var nsInclude map[string]struct{}
-	if len(args.Namespaces) > 1 {
-		nsInclude = args.namespaceSet()
-	}
+) (findings []Finding, again bool) {
+	inScope := args.namespaceInScope()
 	for _, obj := range list.Items {
-		ns := obj.GetNamespace()
-		if nsInclude != nil {
-			if _, ok := nsInclude[ns]; !ok {
-				continue
-			}
-		}
-		if ns != "" && matchAnyPattern(ns, args.ExcludeNamespacePatterns) {
+		if !inScope(obj.GetNamespace()) {
 			continue
 		}
 		counter.checkedResources++
+		if args.needsOwners() {
+			counter.objects = append(counter.objects, newObjectRef(gvr, obj))
+		}
+		optOut := args.optOutOf(obj)
+		addFinding := func(f Finding) {
+			if !args.keepFinding(&f, optOut, counter) {
+				return
+			}
+			if args.WhileRegex != nil {
+				again = true
+			}
+			findings = append(findings, f)
+		}
+		if !args.reportsNamespaceAnnotations(gvr, obj) {
+			for _, msg := range optOut.invalid {
+				f := newFinding(gvr, obj, "Invalid annotation "+msg)
+				f.Type = invalidAnnotationType
+				addFinding(f)
+			}
+		}
 		if args.WarnDeletionTimestampOlderThan > 0 {
 			if dt := obj.GetDeletionTimestamp(); dt != nil && !dt.IsZero() {
-				age := time.Since(dt.Time)
+				age := args.now().Sub(dt.Time)
 				if age > args.WarnDeletionTimestampOlderThan {
-					line := fmt.Sprintf("  %s %s %s DeletionTimestamp set for %s",
-						obj.GetNamespace(), gvr.Resource, obj.GetName(), age.Round(time.Second))
-					if args.WhileRegex == nil || args.WhileRegex.MatchString(line) {
-						if args.WhileRegex != nil {
-							again = true
-						}
-						lines = append(lines, line)
-					}
+					f := newFinding(gvr, obj, fmt.Sprintf("DeletionTimestamp set for %s", age.Round(time.Second)))
+					f.Type = deletionTimestampType
+					f.LastTransitionTime = dt.Time
+					addFinding(f)
 				}
 			}
 		}
-		var conditions []interface{}
-		var err error
-		if gvr.Resource == "hetznerbaremetalhosts" {
-			// For some reasons this resource stores the conditions differently
-			conditions, _, err = unstructured.NestedSlice(obj.Object, "spec", "status", "conditions")
-		} else {
-			conditions, _, err = unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, f := range args.checkers().check(args, gvr, obj) {
+			addFinding(f)
 		}
+		conditions, _, err := conditionsOf(gvr, obj)
 		if err != nil {
 			if strings.Contains(err.Error(), "<nil> is of the type <nil>") {
 				// If we read the manifest before the controller created conditions, then
 				// this can happen.
 				continue
 			}
-			lines = append(lines, fmt.Sprintf("err of unstructured.NestedSlice(%+v): %s",
-				obj.Object, err.Error()))
+			f := newFinding(gvr, obj, fmt.Sprintf("Invalid conditions: %v", err))
+			f.Type = invalidConditionsType
+			addFinding(f)
+		}
+		for i, condition := range conditions {
+			if _, ok := condition.(map[string]interface{}); !ok {
+				f := newFinding(gvr, obj, fmt.Sprintf("Invalid condition #%d: expected an object, got %T", i+1, condition))
+				f.Type = invalidConditionsType
+				addFinding(f)
+			}
+		}
+		if args.LintConditions {
+			for _, f := range lintConditions(gvr, obj, conditions) {
+				addFinding(f)
+			}
 		}
-		subLines, a := printConditions(args, conditions, counter, gvr, obj)
+		if args.StaleAfter > 0 {
+			if f, ok := staleFinding(args, gvr, obj, conditions); ok {
+				addFinding(f)
+			}
+		}
+		if args.flapping != nil {
+			for _, f := range args.flapping.observe(args, gvr, obj, conditions) {
+				addFinding(f)
+			}
+		}
+		subFindings, a := printConditions(args, conditions, counter, gvr, obj, optOut)
 		if a {
 			again = true
 		}
-		lines = append(lines, subLines...)
+		findings = append(findings, subFindings...)
 	}
 	if args.Verbose {
-		fmt.Printf("    checked %s %s %s workerID=%d\n", gvr.Resource, gvr.Group, gvr.Version, workerID)
+		fmt.Fprintf(args.messages(), "    checked %s %s %s workerID=%d\n", gvr.Resource, gvr.Group, gvr.Version, workerID)
+	}
+	return findings, again
+}
+
+// unknownStatus is the status of a condition whose controller can't tell
+// whether it is True or False, for example a node whose kubelet stopped
+// posting its status.
+const unknownStatus = "Unknown"
+
+// unknownInGrace reports whether a finding has status Unknown for less than
+// Arguments.UnknownGrace. Findings without lastTransitionTime are never in
+// grace.
+func (a *Arguments) unknownInGrace(f Finding) bool {
+	if f.Status != unknownStatus || a.UnknownGrace <= 0 || f.LastTransitionTime.IsZero() {
+		return false
+	}
+	return a.now().Sub(f.LastTransitionTime) < a.UnknownGrace
+}
+
+// keepFinding sets the severity of a finding, if it is not set yet, and
+// reports whether the finding is shown: not hidden, and matching
+// Arguments.WhileRegex if it is set.
+func (a *Arguments) keepFinding(f *Finding, optOut objectOptOut, counter *handleResourceTypeOutput) bool {
+	if f.Severity == 0 {
+		f.Severity = a.severityOf(*f, f.Type)
+	}
+	if a.hidden(*f, []string{f.Type}, optOut, counter) {
+		return false
+	}
+	return a.WhileRegex == nil || a.WhileRegex.MatchString(f.Line())
+}
+
+// hidden reports whether a finding is hidden by annotations, silences,
+// UnknownGrace or MinSeverity. Findings hidden by annotations, silences or
+// UnknownGrace are counted.
+func (a *Arguments) hidden(f Finding, types []string, optOut objectOptOut, counter *handleResourceTypeOutput) bool {
+	switch {
+	case optOut.ignores(types, f.LastTransitionTime, a.now()):
+		counter.ignoredFindings++
+	case a.silenced(f, types):
+		counter.silencedFindings++
+	case a.unknownInGrace(f):
+		counter.unknownInGrace++
+	case f.Severity < a.minSeverity():
+	default:
+		return false
+	}
+	return true
+}
+
+// conditionsOf returns the conditions of an object, usually status.conditions.
+func conditionsOf(gvr schema.GroupVersionResource, obj unstructured.Unstructured) ([]interface{}, bool, error) {
+	if gvr.Resource == "hetznerbaremetalhosts" {
+		// For some reasons this resource stores the conditions differently
+		return unstructured.NestedSlice(obj.Object, "spec", "status", "conditions")
+	}
+	return unstructured.NestedSlice(obj.Object, "status", "conditions")
+}
+
+// Finding is a problem of one object, usually an unhealthy condition. Each
+// finding is one line of output.
+type Finding struct {
+	GVR       schema.GroupVersionResource
+	Namespace string
+	Name      string
+	UID       types.UID
+	// Type is the condition type. Merged conditions are joined by "/", for
+	// example "Failed/FailureTarget". Findings which are not about a
+	// condition use a fixed type, for example "DeletionTimestamp".
+	Type string
+	// Status, Reason and Message of the condition.
+	Status   string
+	Reason   string
+	Message  string
+	Severity Severity
+	// LastTransitionTime of the condition, or the deletionTimestamp. Zero if
+	// unknown.
+	LastTransitionTime time.Time
+	// Text is the text after namespace, resource and name, for example
+	// `Condition Ready=False Reason "message" (5m0s)`.
+	Text string
+}
+
+func newFinding(gvr schema.GroupVersionResource, obj unstructured.Unstructured, text string) Finding {
+	return Finding{
+		GVR:       gvr,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		UID:       obj.GetUID(),
+		Text:      text,
+	}
+}
+
+// Line is the output line of the finding. The first three columns can be
+// pasted to "kubectl describe -n".
+func (f Finding) Line() string {
+	return fmt.Sprintf("  %s %s %s %s", f.Namespace, f.GVR.Resource, f.Name, f.TextWithSeverity())
+}
+
+// TextWithSeverity is Text followed by the severity, for example
+// `Condition Ready=False Reason "message" (5m0s) [warning]`.
+func (f Finding) TextWithSeverity() string {
+	if f.Severity == 0 {
+		return f.Text
+	}
+	return fmt.Sprintf("%s [%s]", f.Text, f.Severity)
+}
+
+// sort sorts Lines and Findings alphabetically.
+func (c *Counter) sort() {
+	slices.SortStableFunc(c.Findings, func(a, b Finding) int {
+		return strings.Compare(a.Line(), b.Line())
+	})
+	slices.SortFunc(c.ListErrors, func(a, b Finding) int {
+		return strings.Compare(a.Text, b.Text)
+	})
+	slices.SortStableFunc(c.explanations, func(a, b string) int {
+		return strings.Compare(objectOfExplanation(a), objectOfExplanation(b))
+	})
+	c.Lines = c.Lines[:0]
+	for _, f := range c.Findings {
+		c.Lines = append(c.Lines, f.Line())
 	}
-	return lines, again
 }
 
 type conditionRow struct {
@@ -553,13 +1211,23 @@ type conditionRow struct {
 
 var readyString = "Ready"
 
+// Types of findings which are not about a condition.
+const (
+	deletionTimestampType = "DeletionTimestamp"
+	invalidConditionsType = "InvalidConditions"
+)
+
 // printConditions returns true if the conditions should be checked again N seconds later.
+// optOut are the parsed annotations of obj, see Arguments.optOutOf.
 func printConditions(args *Arguments, conditions []interface{}, counter *handleResourceTypeOutput,
-	gvr schema.GroupVersionResource, obj unstructured.Unstructured,
-) (lines []string, again bool) {
+	gvr schema.GroupVersionResource, obj unstructured.Unstructured, optOut objectOptOut,
+) (findings []Finding, again bool) {
 	var rows []conditionRow
 	for _, condition := range conditions {
-		rows = handleCondition(condition, counter, gvr, rows)
+		rows = handleCondition(args, condition, counter, gvr, rows)
+	}
+	if args.Explain {
+		counter.explainConditions(args, gvr, obj, conditions)
 	}
 	// remove general ready condition, if it is already contained in a particular condition
 	// https://pkg.go.dev/sigs.k8s.io/cluster-api/util/conditions#SetSummary
@@ -580,6 +1248,10 @@ func printConditions(args *Arguments, conditions []interface{}, counter *handleR
 				r.conditionReason == ready.conditionReason &&
 				r.conditionStatus == ready.conditionStatus {
 				skipReadyCondition = true
+				if args.Explain {
+					counter.explain(gvr, obj, stepReadyDedup, "%s: dropped (%s: %s has the same status, reason and message)",
+						explainLabel(readyString, ready.conditionStatus, ready.conditionReason), stepReadyDedup, r.conditionType)
+				}
 				break
 			}
 		}
@@ -617,26 +1289,46 @@ func printConditions(args *Arguments, conditions []interface{}, counter *handleR
 		slices.Sort(e.types)
 		r := e.row
 		r.conditionType = strings.Join(e.types, "/")
+		if args.Explain && len(e.types) > 1 {
+			counter.explain(gvr, obj, stepMerge, "%s: one finding (%s: same status, reason and message)",
+				explainLabel(r.conditionType, r.conditionStatus, r.conditionReason), stepMerge)
+		}
 
 		duration := ""
 		if !r.conditionLastTransitionTime.IsZero() {
-			d := time.Since(r.conditionLastTransitionTime)
+			d := args.now().Sub(r.conditionLastTransitionTime)
 			duration = fmt.Sprint(d.Round(time.Second))
 		}
 
-		outLine := fmt.Sprintf("  %s %s %s Condition %s=%s %s %q (%s)", obj.GetNamespace(), gvr.Resource, obj.GetName(), r.conditionType, r.conditionStatus,
-			r.conditionReason, r.conditionMessage, duration)
+		conditionText := func(conditionType string) string {
+			return fmt.Sprintf("Condition %s=%s %s %q (%s)", conditionType, r.conditionStatus,
+				r.conditionReason, r.conditionMessage, duration)
+		}
+		f := newFinding(gvr, obj, conditionText(r.conditionType))
+		f.Type = r.conditionType
+		f.Status = r.conditionStatus
+		f.Reason = r.conditionReason
+		f.Message = r.conditionMessage
+		f.LastTransitionTime = r.conditionLastTransitionTime
+		// Merged conditions get the highest severity of their types.
+		for _, t := range e.types {
+			f.Severity = max(f.Severity, args.severityOf(f, t))
+		}
+		if args.hidden(f, e.types, optOut, counter) {
+			continue
+		}
+		outLine := f.Line()
 
 		addLine := true
 		if args.WhileRegex != nil {
 			addLine = false
 			// Check each individual type for backward compatibility with --while regexes
 			// that match on a specific condition type name (e.g. "Failed=True").
+			// The lines have the same format as the merged line.
 			for _, t := range e.types {
-				singleLine := fmt.Sprintf("  %s %s %s Condition %s=%s %s %q (%s)",
-					obj.GetNamespace(), gvr.Resource, obj.GetName(),
-					t, r.conditionStatus, r.conditionReason, r.conditionMessage, duration)
-				if args.WhileRegex.MatchString(singleLine) {
+				single := f
+				single.Text = conditionText(t)
+				if args.WhileRegex.MatchString(single.Line()) {
 					again = true
 					addLine = true
 					break
@@ -650,44 +1342,25 @@ func printConditions(args *Arguments, conditions []interface{}, counter *handleR
 		}
 
 		if addLine {
-			lines = append(lines, outLine)
+			findings = append(findings, f)
 		}
 	}
-	return lines, again
+	return findings, again
 }
 
-func handleCondition(condition interface{}, counter *handleResourceTypeOutput, gvr schema.GroupVersionResource, rows []conditionRow) []conditionRow {
+func handleCondition(args *Arguments, condition interface{}, counter *handleResourceTypeOutput, gvr schema.GroupVersionResource, rows []conditionRow) []conditionRow {
 	conditionMap, ok := condition.(map[string]interface{})
 	if !ok {
-		fmt.Println("Invalid condition format")
+		// Reported by printResources.
 		return rows
 	}
 	counter.checkedConditions++
 
 	conditionType, _ := conditionMap["type"].(string)
 	conditionStatus, _ := conditionMap["status"].(string)
-	if conditionToSkip(conditionType) {
-		return rows
-	}
-	switch conditionStatus {
-	case "True":
-		if conditionTypeHasPositiveMeaning(gvr.Resource, conditionType) {
-			return rows
-		}
-	case "False":
-		if conditionTypeHasNegativeMeaning(gvr.Resource, conditionType) {
-			return rows
-		}
-	}
 	conditionReason, _ := conditionMap["reason"].(string)
 	conditionMessage, _ := conditionMap["message"].(string)
-	conditionLine := fmt.Sprintf("%s %s=%s %s %q", gvr.Resource, conditionType, conditionStatus, conditionReason, conditionMessage)
-	for _, r := range conditionLinesToIgnoreRegexs {
-		if r.MatchString(conditionLine) {
-			return rows
-		}
-	}
-	if conditionDone(conditionType, conditionStatus, conditionReason) {
+	if args.classifyCondition(gvr, conditionType, conditionStatus, conditionReason, conditionMessage) != verdictUnhealthy {
 		return rows
 	}
 	s, _ := conditionMap["lastTransitionTime"].(string)
@@ -702,6 +1375,58 @@ func handleCondition(condition interface{}, counter *handleResourceTypeOutput, g
 	return rows
 }
 
+// Verdicts of classifyCondition.
+const (
+	verdictHealthy   = "healthy"
+	verdictUnhealthy = "unhealthy"
+	verdictSkipped   = "skipped"
+)
+
+// classifyCondition returns how the rules treat a condition, see
+// explainCondition.
+func (a *Arguments) classifyCondition(gvr schema.GroupVersionResource, conditionType, conditionStatus, conditionReason, conditionMessage string) string {
+	return a.explainCondition(gvr, conditionType, conditionStatus, conditionReason, conditionMessage).verdict
+}
+
+// explainCondition classifies a condition: The first matching rule of
+// Arguments.ConditionRules decides. Otherwise it is skipped if its type is
+// in the skip list or the line matches conditionLinesToIgnoreRegexs, healthy
+// if the status is the good one for the type, otherwise unhealthy. The
+// decision contains the step which decided, see Arguments.Explain.
+func (a *Arguments) explainCondition(gvr schema.GroupVersionResource, conditionType, conditionStatus, conditionReason, conditionMessage string) decision {
+	resource := gvr.Resource
+	conditionLine := fmt.Sprintf("%s %s=%s %s %q", resource, conditionType, conditionStatus, conditionReason, conditionMessage)
+	for i := range a.ConditionRules {
+		if verdict, ok := a.ConditionRules[i].verdict(gvr, conditionType, conditionStatus, conditionLine); ok {
+			return decision{verdict, "condition rule " + ruleName(a.ConditionRules[i].Name, i)}
+		}
+	}
+	if conditionToSkip(conditionType) {
+		return decision{verdictSkipped, stepSkipList}
+	}
+	switch conditionStatus {
+	case "True":
+		if step := positiveMeaningStep(resource, conditionType); step != "" {
+			return decision{verdictHealthy, step}
+		}
+	case "False":
+		if step := negativeMeaningStep(resource, conditionType); step != "" {
+			return decision{verdictHealthy, step}
+		}
+	case unknownStatus:
+		// Unknown is never healthy, see Arguments.UnknownGrace.
+	}
+	for _, r := range conditionLinesToIgnoreRegexs {
+		if r.MatchString(conditionLine) {
+			return decision{verdictSkipped, "ignore regex " + r.String()}
+		}
+	}
+	if conditionDone(conditionType, conditionStatus, conditionReason) {
+		return decision{verdictHealthy, stepConditionDone}
+	}
+	return decision{verdictUnhealthy, stepNoRule}
+}
+
 func conditionToSkip(ct string) bool {
 	// Skip conditions which can be True or False, and both values are fine.
 	toSkip := []string{
@@ -838,10 +1563,12 @@ var conditionLinesToIgnoreRegexs = []*regexp.Regexp{
 	regexp.MustCompile(`perconaxtradbclusters tls=enabled`),
 }
 
-func conditionTypeHasPositiveMeaning(resource string, ct string) bool {
+// positiveMeaningStep returns the step of --explain which makes status True
+// of the condition type healthy, or "".
+func positiveMeaningStep(resource string, ct string) string {
 	types := conditionTypesOfResourceWithPositiveMeaning[resource]
 	if slices.Contains(types, ct) {
-		return true
+		return "positive meaning map " + resource
 	}
 
 	for _, suffix := range []string{
@@ -881,17 +1608,17 @@ func conditionTypeHasPositiveMeaning(resource string, ct string) bool {
 		"ReconcileSuccess",      // rabbitmqclusters
 	} {
 		if strings.HasSuffix(ct, suffix) {
-			return true
+			return "positive suffix " + suffix
 		}
 	}
 	for _, prefix := range []string{
 		"Created",
 	} {
 		if strings.HasPrefix(ct, prefix) {
-			return true
+			return "positive prefix " + prefix
 		}
 	}
-	return false
+	return ""
 }
 
 func conditionDone(conditionType string, conditionStatus string, conditionReason string) bool {
@@ -910,32 +1637,39 @@ func conditionDone(conditionType string, conditionStatus string, conditionReason
 	return false
 }
 
-func conditionTypeHasNegativeMeaning(resource string, ct string) bool {
+// negativeMeaningStep returns the step of --explain which makes status False
+// of the condition type healthy, or "".
+func negativeMeaningStep(resource string, ct string) string {
 	types := conditionTypesOfResourceWithNegativeMeaning[resource]
 	if slices.Contains(types, ct) {
-		return true
+		return "negative meaning map " + resource
 	}
 
 	for _, suffix := range []string{
 		"Unavailable", "Pressure", "Dangling", "Unhealthy", "Paused", "Deleting", "Failed",
 	} {
 		if strings.HasSuffix(ct, suffix) {
-			return true
+			return "negative suffix " + suffix
 		}
 	}
 	if strings.HasPrefix(ct, "Frequent") && strings.HasSuffix(ct, "Restart") {
-		return true
+		return "negative prefix Frequent*Restart"
 	}
 
-	return false
+	return ""
 }
 
 type handleResourceTypeInput struct {
-	args       *Arguments
-	dynClient  *dynamic.DynamicClient
+	args      *Arguments
+	dynClient dynamic.Interface
+	// protobuf is only set with Arguments.Protobuf.
+	protobuf   *protobufLister
 	gvr        schema.GroupVersionResource
+	kind       string
 	workerID   int32
 	namespaced bool
+	// prioritized types had findings in previous runs, see Arguments.Priority.
+	prioritized bool
 }
 
 type handleResourceTypeOutput struct {
@@ -943,10 +1677,31 @@ type handleResourceTypeOutput struct {
 	checkedResources     int32
 	checkedConditions    int32
 	whileRegexDidMatch   bool
-	lines                []string
+	findings             []Finding
+	// ignoredFindings were dropped because of annotations, see AnnotationIgnore.
+	ignoredFindings int32
+	// silencedFindings were dropped because of a Silence.
+	silencedFindings int32
+	// unknownInGrace were dropped because of Arguments.UnknownGrace.
+	unknownInGrace int32
+	// objects is only set if Arguments.needsOwners.
+	objects []objectRef
 	// forbiddenResource is the resource name when listing was rejected with a
 	// 403 Forbidden. Aggregated by the caller into a single summary line.
 	forbiddenResource string
+	// notFound is true if listing failed with 404 Not Found.
+	notFound bool
+	// explanations and ruleHits are only set with Arguments.Explain.
+	explanations []string
+	ruleHits     map[string]int
+	// listError is set if listing failed, except with 403 Forbidden.
+	listError     *Finding
+	listedObjects int
+	gvr           schema.GroupVersionResource
+	listDuration  time.Duration
+	prioritized   bool
+	// list is only set with Arguments.keepLists.
+	list *unstructured.UnstructuredList
 }
 
 func handleResourceType(ctx context.Context, input handleResourceTypeInput) handleResourceTypeOutput {
@@ -956,11 +1711,7 @@ func handleResourceType(ctx context.Context, input handleResourceTypeInput) hand
 	name := input.gvr.Resource
 	dynClient := input.dynClient
 	gvr := input.gvr
-	// Skip subresources like pod/logs, pod/status
-	if containsSlash(name) {
-		return output
-	}
-	if slices.Contains(resourcesToSkip, gvr.GroupResource()) {
+	if skipResourceType(gvr) {
 		return output
 	}
 
@@ -970,12 +1721,14 @@ func handleResourceType(ctx context.Context, input handleResourceTypeInput) hand
 
 	namespaceable := dynClient.Resource(gvr)
 	var resourceInterface dynamic.ResourceInterface
+	namespace := metav1.NamespaceAll
 	if input.namespaced {
 		// One namespace and no excludes: filter on the server. Otherwise list
 		// cluster-wide and apply include/exclude filters in printResources.
 		canServerSideFilter := len(args.Namespaces) == 1 && len(args.ExcludeNamespacePatterns) == 0
 		if canServerSideFilter {
-			resourceInterface = namespaceable.Namespace(args.Namespaces[0])
+			namespace = args.Namespaces[0]
+			resourceInterface = namespaceable.Namespace(namespace)
 		} else if len(args.Namespaces) == 1 && matchAnyPattern(args.Namespaces[0], args.ExcludeNamespacePatterns) {
 			// Single included namespace is itself excluded — nothing to do.
 			return output
@@ -985,21 +1738,59 @@ func handleResourceType(ctx context.Context, input handleResourceTypeInput) hand
 	} else {
 		resourceInterface = namespaceable
 	}
+	var lister resourceLister = resourceInterface
+	if input.protobuf != nil && input.protobuf.supports(gvr, input.kind) {
+		lister = input.protobuf.resource(gvr, input.kind, namespace, args.reduceProtobuf())
+	}
 
-	list, err := resourceInterface.List(ctx, metav1.ListOptions{})
+	start := time.Now()
+	list, err := listWithRetries(ctx, args, lister)
+	listDuration := time.Since(start)
 	if err != nil {
 		if apierrors.IsForbidden(err) {
 			output.forbiddenResource = name
 			return output
 		}
-		fmt.Printf("..Error listing %s: %v. group %q version %q resource %q\n", name, err,
-			gvr.Group, gvr.Version, gvr.Resource)
+		output.notFound = apierrors.IsNotFound(err)
+		f := newListErrorFinding(gvr, err)
+		f.Severity = args.severityOf(f, f.Type)
+		output.listError = &f
 		return output
 	}
 
+	output = checkList(args, gvr, list, input.workerID)
+	output.listDuration = listDuration
+	output.listedObjects = len(list.Items)
+	output.prioritized = input.prioritized
+	return output
+}
+
+// skipResourceType reports whether resources of this type are never checked.
+func skipResourceType(gvr schema.GroupVersionResource) bool {
+	// Skip subresources like pod/logs, pod/status
+	if containsSlash(gvr.Resource) {
+		return true
+	}
+	return slices.Contains(resourcesToSkip, gvr.GroupResource())
+}
+
+// checkList checks the objects of one resource type, no matter whether they
+// were listed from the API server or read from a file.
+func checkList(args *Arguments, gvr schema.GroupVersionResource, list *unstructured.UnstructuredList, workerID int32) handleResourceTypeOutput {
+	var output handleResourceTypeOutput
 	output.checkedResourceTypes++
-	lines, again := printResources(args, list, gvr, &output, input.workerID)
+	findings, again := printResources(args, list, gvr, &output, workerID)
 	output.whileRegexDidMatch = again
-	output.lines = lines
+	output.findings = findings
+	output.gvr = gvr
+	if args.keepLists {
+		output.list = &unstructured.UnstructuredList{}
+		inScope := args.namespaceInScope()
+		for _, obj := range list.Items {
+			if inScope(obj.GetNamespace()) {
+				output.list.Items = append(output.list.Items, obj)
+			}
+		}
+	}
 	return output
 }
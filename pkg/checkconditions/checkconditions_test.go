@@ -1,6 +1,7 @@
 package checkconditions
 
 import (
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -31,7 +32,7 @@ func TestHandleConditionSkipsHealthyMachineConditions(t *testing.T) {
 
 	var rows []conditionRow
 	for _, condition := range tests {
-		rows = handleCondition(condition, counter, gvr, rows)
+		rows = handleCondition(&Arguments{}, condition, counter, gvr, rows)
 	}
 
 	if len(rows) != 0 {
@@ -64,13 +65,41 @@ func TestPrintConditionsMergesDuplicateReasonMessage(t *testing.T) {
 		},
 	}
 	counter := &handleResourceTypeOutput{}
-	lines, _ := printConditions(args, conditions, counter, gvr, obj)
+	lines, _ := printConditions(args, conditions, counter, gvr, obj, args.optOutOf(obj))
 
 	if len(lines) != 1 {
 		t.Fatalf("expected 1 merged line, got %d: %v", len(lines), lines)
 	}
-	if !strings.Contains(lines[0], "Failed/FailureTarget=True") {
-		t.Errorf("expected merged condition types in output, got: %s", lines[0])
+	if !strings.Contains(lines[0].Line(), "Failed/FailureTarget=True") {
+		t.Errorf("expected merged condition types in output, got: %s", lines[0].Line())
+	}
+}
+
+func TestPrintConditionsWhileRegexMatchesSeverity(t *testing.T) {
+	gvr := schema.GroupVersionResource{Resource: "jobs"}
+	condition := func(conditionType string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":    conditionType,
+			"status":  "True",
+			"reason":  "BackoffLimitExceeded",
+			"message": "Job has reached the specified backoff limit",
+		}
+	}
+	obj := unstructured.Unstructured{}
+	obj.SetName("my-job")
+	obj.SetNamespace("agentloop")
+
+	// The line of a single type of a merged finding has the severity suffix
+	// like the merged line.
+	args := &Arguments{WhileRegex: regexp.MustCompile(`Condition Failed=True .* \[[a-z]+\]$`)}
+	for _, conditions := range [][]interface{}{
+		{condition("Failed")},
+		{condition("Failed"), condition("FailureTarget")},
+	} {
+		lines, again := printConditions(args, conditions, &handleResourceTypeOutput{}, gvr, obj, args.optOutOf(obj))
+		if !again || len(lines) != 1 {
+			t.Errorf("%d conditions: expected the regex to match, got %v", len(conditions), lines)
+		}
 	}
 }
 
@@ -93,8 +122,8 @@ func TestPrintResourcesWarnsDeletionTimestamp(t *testing.T) {
 	if len(lines) == 0 {
 		t.Fatal("expected a warning line for old deletionTimestamp, got none")
 	}
-	if !strings.Contains(lines[0], "DeletionTimestamp") {
-		t.Errorf("expected line to mention DeletionTimestamp, got: %s", lines[0])
+	if !strings.Contains(lines[0].Line(), "DeletionTimestamp") {
+		t.Errorf("expected line to mention DeletionTimestamp, got: %s", lines[0].Line())
 	}
 }
 
@@ -115,8 +144,8 @@ func TestPrintResourcesNoWarnRecentDeletionTimestamp(t *testing.T) {
 	lines, _ := printResources(args, list, gvr, counter, 0)
 
 	for _, l := range lines {
-		if strings.Contains(l, "DeletionTimestamp") {
-			t.Errorf("expected no warning for recent deletionTimestamp, got: %s", l)
+		if strings.Contains(l.Line(), "DeletionTimestamp") {
+			t.Errorf("expected no warning for recent deletionTimestamp, got: %s", l.Line())
 		}
 	}
 }
@@ -138,8 +167,8 @@ func TestPrintResourcesDisabledDeletionTimestampCheck(t *testing.T) {
 	lines, _ := printResources(args, list, gvr, counter, 0)
 
 	for _, l := range lines {
-		if strings.Contains(l, "DeletionTimestamp") {
-			t.Errorf("expected no warning when check is disabled, got: %s", l)
+		if strings.Contains(l.Line(), "DeletionTimestamp") {
+			t.Errorf("expected no warning when check is disabled, got: %s", l.Line())
 		}
 	}
 }
@@ -0,0 +1,107 @@
+package checkconditions
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ObjectChecker checks an object in addition to its conditions. It is called
+// for every object of every resource type, so it should return quickly for
+// objects it does not care about.
+//
+// A checker only needs to set Type and Text of a finding. GVR, Namespace,
+// Name and UID are taken from the object if empty. If Severity is zero, the
+// rules decide (see Rule), like for conditions. Annotations, silences,
+// --min-severity and the while-regex apply to the findings as usual.
+type ObjectChecker interface {
+	Check(gvr schema.GroupVersionResource, obj unstructured.Unstructured) []Finding
+}
+
+// ObjectCheckerFunc is a function which implements ObjectChecker.
+type ObjectCheckerFunc func(gvr schema.GroupVersionResource, obj unstructured.Unstructured) []Finding
+
+func (f ObjectCheckerFunc) Check(gvr schema.GroupVersionResource, obj unstructured.Unstructured) []Finding {
+	return f(gvr, obj)
+}
+
+// CheckerRegistry holds named checkers. Checkers are called in the order
+// they were registered.
+type CheckerRegistry struct {
+	mu       sync.RWMutex
+	names    []string
+	checkers []ObjectChecker
+}
+
+// Register adds a checker. It panics if the name is already registered, like
+// database/sql.Register.
+func (r *CheckerRegistry) Register(name string, checker ObjectChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if checker == nil {
+		panic("checkconditions: Register checker is nil")
+	}
+	for _, n := range r.names {
+		if n == name {
+			panic(fmt.Sprintf("checkconditions: Register called twice for checker %q", name))
+		}
+	}
+	r.names = append(r.names, name)
+	r.checkers = append(r.checkers, checker)
+}
+
+// Names returns the names of the registered checkers.
+func (r *CheckerRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.names...)
+}
+
+// check runs all checkers on the object and completes their findings.
+func (r *CheckerRegistry) check(args *Arguments, gvr schema.GroupVersionResource, obj unstructured.Unstructured) []Finding {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var findings []Finding
+	for _, c := range r.checkers {
+		for _, f := range c.Check(gvr, obj) {
+			if f.GVR.Empty() {
+				f.GVR = gvr
+			}
+			if f.Name == "" {
+				f.Namespace, f.Name, f.UID = obj.GetNamespace(), obj.GetName(), obj.GetUID()
+			}
+			if f.Text == "" {
+				f.Text = f.Type
+			}
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// DefaultCheckers is used if Arguments.Checkers is nil.
+var DefaultCheckers = &CheckerRegistry{}
+
+// RegisterChecker adds a checker to DefaultCheckers. A custom binary can
+// register its checkers in an init function and then call cmd.Execute():
+//
+//	func init() {
+//		checkconditions.RegisterChecker("my-crd", myChecker{})
+//	}
+//
+//	func main() {
+//		cmd.Execute()
+//	}
+func RegisterChecker(name string, checker ObjectChecker) {
+	DefaultCheckers.Register(name, checker)
+}
+
+// checkers returns Arguments.Checkers or DefaultCheckers.
+func (a *Arguments) checkers() *CheckerRegistry {
+	if a.Checkers != nil {
+		return a.Checkers
+	}
+	return DefaultCheckers
+}
@@ -0,0 +1,84 @@
+package checkconditions
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// zeroReplicasChecker reports widgets with spec.replicas 0.
+var zeroReplicasChecker = ObjectCheckerFunc(func(gvr schema.GroupVersionResource, obj unstructured.Unstructured) []Finding {
+	if gvr.Resource != "widgets" {
+		return nil
+	}
+	replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found || replicas != 0 {
+		return nil
+	}
+	return []Finding{{Type: "ZeroReplicas", Text: "spec.replicas is 0"}}
+})
+
+func TestObjectChecker(t *testing.T) {
+	registry := &CheckerRegistry{}
+	registry.Register("zero-replicas", zeroReplicasChecker)
+	registry.Register("critical", ObjectCheckerFunc(func(gvr schema.GroupVersionResource, obj unstructured.Unstructured) []Finding {
+		if obj.GetName() != "widget-b" {
+			return nil
+		}
+		return []Finding{{Type: "Custom", Text: "custom problem", Severity: SeverityCritical}}
+	}))
+	if got := registry.Names(); strings.Join(got, ",") != "zero-replicas,critical" {
+		t.Fatalf("unexpected names %v", got)
+	}
+
+	widget := func(name string, replicas int64) unstructured.Unstructured {
+		obj := newTestObject(widgetsGVR, "Widget", "team-a", name)
+		if err := unstructured.SetNestedField(obj.Object, replicas, "spec", "replicas"); err != nil {
+			t.Fatal(err)
+		}
+		return *obj
+	}
+	lists := []resourceList{{gvr: widgetsGVR, list: &unstructured.UnstructuredList{Items: []unstructured.Unstructured{
+		widget("widget-a", 0), widget("widget-b", 1), widget("widget-c", 0),
+	}}}}
+	lists[0].list.Items[2].SetAnnotations(map[string]string{AnnotationIgnore: "ZeroReplicas"})
+
+	args := &Arguments{Checkers: registry}
+	counter, err := checkResourceLists(args, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"  team-a widgets widget-a spec.replicas is 0 [warning]",
+		"  team-a widgets widget-b custom problem [critical]",
+	}
+	if strings.Join(counter.Lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected lines:\n%s", strings.Join(counter.Lines, "\n"))
+	}
+	if counter.IgnoredFindings != 1 {
+		t.Errorf("expected the annotation to apply to checker findings, got %d ignored", counter.IgnoredFindings)
+	}
+
+	// Rules apply to findings of checkers, too.
+	args = &Arguments{Checkers: registry, Rules: []Rule{{Type: "ZeroReplicas", Severity: SeverityInfo}}, MinSeverity: SeverityWarning}
+	counter, err = checkResourceLists(args, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.Lines) != 1 || !strings.Contains(counter.Lines[0], "widget-b") {
+		t.Errorf("expected only the critical finding, got %v", counter.Lines)
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	registry := &CheckerRegistry{}
+	registry.Register("zero-replicas", zeroReplicasChecker)
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	registry.Register("zero-replicas", zeroReplicasChecker)
+}
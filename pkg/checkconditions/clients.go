@@ -0,0 +1,73 @@
+package checkconditions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+)
+
+// Clients bundles the API clients used by a scan. Everything above the
+// rest config only talks to these interfaces, so tests can pass client-go
+// fakes instead of a running cluster.
+type Clients struct {
+	// Kubernetes is used to list namespaces when expanding -n glob patterns.
+	Kubernetes kubernetes.Interface
+	Dynamic    dynamic.Interface
+	Discovery  discovery.DiscoveryInterface
+	// CacheKey identifies the cluster in the user's cache directory. Empty
+	// disables all caches.
+	CacheKey string
+	// protobuf is used with Arguments.Protobuf. Nil (for example with
+	// fakes) means JSON for all types.
+	protobuf *protobufLister
+}
+
+// NewClients creates the clients for a scan from a rest config. No request is
+// sent to the API server.
+func NewClients(config *restclient.Config) (*Clients, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating clientset: %w", err)
+	}
+
+	dynClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating dynamic client: %w", err)
+	}
+
+	return &Clients{
+		Kubernetes: clientset,
+		Dynamic:    dynClient,
+		Discovery:  clientset.Discovery(),
+		CacheKey:   clusterCacheKey(config.Host),
+		protobuf:   newProtobufLister(config),
+	}, nil
+}
+
+var cacheKeyInvalidChars = regexp.MustCompile(`[^\w.-]`)
+
+// clusterCacheKey turns the API server URL into a directory name, like
+// kubectl does for its discovery cache: https://10.0.0.1:6443 -> 10.0.0.1_6443
+func clusterCacheKey(host string) string {
+	key := strings.TrimPrefix(host, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	key = strings.ReplaceAll(key, "/", "_")
+	return cacheKeyInvalidChars.ReplaceAllString(key, "_")
+}
+
+// cacheDir returns the directory for cached state of the cluster, below
+// $XDG_CACHE_HOME/check-conditions.
+func cacheDir(cacheKey string) (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "check-conditions", cacheKey), nil
+}
@@ -0,0 +1,174 @@
+package checkconditions
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// defaultWorkers is used if Arguments.Workers is not set.
+const defaultWorkers = 10
+
+// workers returns Arguments.Workers, or defaultWorkers.
+func (a *Arguments) workers() int {
+	if a.Workers > 0 {
+		return a.Workers
+	}
+	return defaultWorkers
+}
+
+// Headers of API Priority and Fairness. The API server sets them on
+// responses which were classified by APF, including its 429 responses.
+const (
+	apfFlowSchemaHeader    = "X-Kubernetes-PF-FlowSchema-UID"
+	apfPriorityLevelHeader = "X-Kubernetes-PF-PriorityLevel-UID"
+)
+
+// adaptiveLimiter limits the number of concurrent LIST requests. The limit
+// is halved when the API server answers with 429 Too Many Requests, at most
+// once per cooldown, and grows by one after limit successful requests
+// (additive increase, multiplicative decrease).
+type adaptiveLimiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	limit     int
+	max       int
+	inFlight  int
+	successes int
+	// throttled counts the 429 responses, throttledByAPF those with APF
+	// headers.
+	throttled      int
+	throttledByAPF int
+	lastDecrease   time.Time
+	cooldown       time.Duration
+}
+
+func newAdaptiveLimiter(max int) *adaptiveLimiter {
+	l := &adaptiveLimiter{limit: max, max: max, cooldown: time.Second}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire waits for a free slot. The returned function releases it.
+func (l *adaptiveLimiter) acquire() func() {
+	l.mu.Lock()
+	for l.inFlight >= l.limit {
+		l.cond.Wait()
+	}
+	l.inFlight++
+	l.mu.Unlock()
+	return func() {
+		l.mu.Lock()
+		l.inFlight--
+		l.mu.Unlock()
+		l.cond.Broadcast()
+	}
+}
+
+// observe is called for each response of the API server.
+func (l *adaptiveLimiter) observe(resp *http.Response, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		l.successes++
+		if l.successes >= l.limit && l.limit < l.max {
+			l.limit++
+			l.successes = 0
+			l.cond.Broadcast()
+		}
+		return
+	}
+	l.throttled++
+	priorityLevel := resp.Header.Get(apfPriorityLevelHeader)
+	if priorityLevel != "" || resp.Header.Get(apfFlowSchemaHeader) != "" {
+		l.throttledByAPF++
+	}
+	l.successes = 0
+	if now.Sub(l.lastDecrease) < l.cooldown || l.limit == 1 {
+		return
+	}
+	l.limit = max(1, l.limit/2)
+	l.lastDecrease = now
+	via := ""
+	if priorityLevel != "" {
+		via = fmt.Sprintf(" (APF priority level %s)", priorityLevel)
+	}
+	fmt.Fprintf(os.Stderr, "Throttled by the API server%s, reducing concurrency to %d\n", via, l.limit)
+}
+
+// String returns the current limit and how often the API server throttled.
+func (l *adaptiveLimiter) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return fmt.Sprintf("%d of %d, throttled %d times (%d by APF)", l.limit, l.max, l.throttled, l.throttledByAPF)
+}
+
+// adaptiveTransport reports every response to the limiter. client-go retries
+// 429 responses itself, so the errors don't reach listWithRetries.
+type adaptiveTransport struct {
+	limiter *adaptiveLimiter
+	next    http.RoundTripper
+}
+
+func (t *adaptiveTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		t.limiter.observe(resp, time.Now())
+	}
+	return resp, err
+}
+
+// limiter returns the limiter of Arguments.Adaptive, or nil. It is created
+// before the first request and lives as long as the Arguments, so "forever"
+// keeps a reduced limit.
+func (a *Arguments) limiter() *adaptiveLimiter {
+	if a.Adaptive && a.adaptiveLimiter == nil {
+		a.adaptiveLimiter = newAdaptiveLimiter(a.workers())
+	}
+	return a.adaptiveLimiter
+}
+
+// acquireSlot waits until the limiter allows one more request. Without
+// Arguments.Adaptive the number of workers is the only limit.
+func (a *Arguments) acquireSlot() func() {
+	if a.adaptiveLimiter == nil {
+		return func() {}
+	}
+	return a.adaptiveLimiter.acquire()
+}
+
+// writeStats writes the LIST duration and the number of objects per
+// resource type, slowest first.
+func writeStats(w io.Writer, args *Arguments, counter *Counter) {
+	stats := slices.Clone(counter.typeStats)
+	slices.SortFunc(stats, func(a, b resourceTypeStat) int {
+		switch {
+		case a.listDuration > b.listDuration:
+			return -1
+		case a.listDuration < b.listDuration:
+			return 1
+		}
+		return strings.Compare(a.gvr.String(), b.gvr.String())
+	})
+	var total time.Duration
+	var objects int
+	for _, s := range stats {
+		total += s.listDuration
+		objects += s.objects
+	}
+	fmt.Fprintf(w, "LIST statistics: %d types, %d objects, %s in total, %d workers\n",
+		len(stats), objects, total.Round(time.Millisecond), args.workers())
+	for _, s := range stats {
+		fmt.Fprintf(w, "  %10s %7d objects %5d findings  %s\n",
+			s.listDuration.Round(time.Millisecond), s.objects, s.lines, s.gvr.GroupResource())
+	}
+	if l := args.limiter(); l != nil {
+		fmt.Fprintf(w, "Adaptive concurrency: %s\n", l)
+	}
+}
@@ -0,0 +1,122 @@
+package checkconditions
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestConfigRateLimit(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "kubeconfig")
+	writeFile(t, kubeconfig, `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+current-context: test
+`)
+	t.Setenv("KUBECONFIG", kubeconfig)
+	config, err := (&Arguments{}).RestConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.QPS != 1000 || config.Burst != 1000 {
+		t.Errorf("expected the default rate limit, got %v/%v", config.QPS, config.Burst)
+	}
+	config, err = (&Arguments{QPS: 20, Burst: 40}).RestConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.QPS != 20 || config.Burst != 40 {
+		t.Errorf("expected --qps 20 --burst 40, got %v/%v", config.QPS, config.Burst)
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := newAdaptiveLimiter(8)
+	throttled := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	throttled.Header.Set(apfPriorityLevelHeader, "workload-low")
+	ok := &http.Response{StatusCode: http.StatusOK}
+
+	l.observe(throttled, now)
+	l.observe(throttled, now.Add(100*time.Millisecond))
+	if l.limit != 4 {
+		t.Fatalf("expected one decrease within the cooldown, got limit %d", l.limit)
+	}
+	l.observe(throttled, now.Add(2*time.Second))
+	if l.limit != 2 || l.throttled != 3 || l.throttledByAPF != 3 {
+		t.Fatalf("unexpected limiter %s", l)
+	}
+	l.observe(ok, now)
+	l.observe(ok, now)
+	if l.limit != 3 {
+		t.Errorf("expected the limit to grow after 2 successes, got %d", l.limit)
+	}
+
+	// Only one request at a time.
+	l = newAdaptiveLimiter(1)
+	release := l.acquire()
+	acquired := make(chan struct{})
+	go func() {
+		l.acquire()()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("expected the second acquire to wait")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release()
+	<-acquired
+}
+
+func TestAdaptiveTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(apfFlowSchemaHeader, "some-uid")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	args := &Arguments{Adaptive: true, Workers: 4}
+	client := &http.Client{Transport: &adaptiveTransport{limiter: args.limiter(), next: http.DefaultTransport}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := args.limiter().String(); got != "2 of 4, throttled 1 times (1 by APF)" {
+		t.Errorf("unexpected limiter %s", got)
+	}
+}
+
+func TestStats(t *testing.T) {
+	cluster := defaultTestCluster()
+	args := &Arguments{Workers: 2, Adaptive: true, Stats: true}
+	counter, err := RunAndGetCounterWithClients(context.Background(), cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	writeStats(&buf, args, &counter)
+	out := buf.String()
+	for _, s := range []string{
+		"LIST statistics: 3 types, 5 objects, ",
+		"      3 objects     2 findings  pods\n",
+		"      1 objects     1 findings  widgets.example.com\n",
+		"Adaptive concurrency: 2 of 2, throttled 0 times (0 by APF)\n",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in:\n%s", s, out)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package checkconditions
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// coreGroup is the name of the core API group (pods, nodes, ...) in
+// ConditionRule.Group.
+const coreGroup = "core"
+
+// ConditionRule changes the classification of conditions, see
+// Arguments.classifyCondition. Exactly one of Healthy, Skip and Ignore is
+// set. The "learn" sub-command writes them.
+type ConditionRule struct {
+	// Name is shown in error messages only.
+	Name string `json:"name,omitempty"`
+	// Group is a glob pattern for the API group, for example
+	// "cluster.x-k8s.io", or "core" for pods, nodes and the other types of
+	// the core group. Empty means all groups.
+	Group string `json:"group,omitempty"`
+	// Resource and Type are glob patterns, like in Rule.
+	Resource string `json:"resource,omitempty"`
+	Type     string `json:"type,omitempty"`
+	// Healthy is the status which is fine, "True" or "False". This is the
+	// polarity of the condition type: the other status is unhealthy.
+	Healthy string `json:"healthy,omitempty"`
+	// Skip means that both statuses are fine, like conditionToSkip.
+	Skip bool `json:"skip,omitempty"`
+	// Ignore is a regular expression for the condition line, like
+	// conditionLinesToIgnoreRegexs:
+	//
+	//	resource Type=Status Reason "message"
+	Ignore string `json:"ignore,omitempty"`
+
+	ignore *regexp.Regexp
+}
+
+// compile validates the rule and compiles the regular expression.
+func (r *ConditionRule) compile() error {
+	for _, p := range []string{r.Group, r.Resource, r.Type} {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	set := 0
+	for _, ok := range []bool{r.Healthy != "", r.Skip, r.Ignore != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("exactly one of healthy, skip and ignore is needed")
+	}
+	switch {
+	case r.Healthy != "" && r.Healthy != "True" && r.Healthy != "False":
+		return fmt.Errorf("invalid healthy status %q, supported: True, False", r.Healthy)
+	case r.Ignore == "" && r.Type == "":
+		return errors.New("type is missing")
+	}
+	if r.Ignore != "" {
+		var err error
+		if r.ignore, err = regexp.Compile(r.Ignore); err != nil {
+			return fmt.Errorf("invalid ignore regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// verdict returns how the rule classifies a condition, and false if the rule
+// does not match. conditionLine is the line which Ignore matches.
+func (r *ConditionRule) verdict(gvr schema.GroupVersionResource, conditionType, conditionStatus, conditionLine string) (string, bool) {
+	if r.Group != "" && !matchAnyPattern(groupOfRule(gvr.Group), []string{r.Group}) {
+		return "", false
+	}
+	if r.Resource != "" && !matchAnyPattern(gvr.Resource, []string{r.Resource}) {
+		return "", false
+	}
+	if r.Type != "" && !matchAnyPattern(conditionType, []string{r.Type}) {
+		return "", false
+	}
+	switch {
+	case r.Skip:
+		return verdictSkipped, true
+	case r.ignore != nil:
+		if r.ignore.MatchString(conditionLine) {
+			return verdictSkipped, true
+		}
+	case conditionStatus == r.Healthy:
+		return verdictHealthy, true
+	case conditionStatus == "True" || conditionStatus == "False":
+		return verdictUnhealthy, true
+	}
+	// Unknown is decided by the builtin classification.
+	return "", false
+}
+
+// groupOfRule returns the group like ConditionRule.Group names it.
+func groupOfRule(group string) string {
+	if group == "" {
+		return coreGroup
+	}
+	return group
+}
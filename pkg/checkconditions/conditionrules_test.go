@@ -0,0 +1,127 @@
+package checkconditions
+
+import (
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestLoadRulesFileConditions(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	writeFile(t, valid, `conditions:
+- resource: widgets
+  type: Synced
+  healthy: "False"
+- type: Flaky*
+  skip: true
+- ignore: '^pods Ready=False Evicted '
+`)
+	file, err := LoadRulesFile(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Rules) != 0 || len(file.Conditions) != 3 {
+		t.Fatalf("unexpected rules %+v", file)
+	}
+
+	for name, content := range map[string]string{
+		"typo":    "conditions:\n- tpye: Ready\n  skip: true\n",
+		"none":    "conditions:\n- type: Ready\n",
+		"two":     "conditions:\n- type: Ready\n  skip: true\n  healthy: \"True\"\n",
+		"healthy": "conditions:\n- type: Ready\n  healthy: Unknown\n",
+		"no-type": "conditions:\n- skip: true\n",
+		"regex":   "conditions:\n- ignore: \"(\"\n",
+		"group":   "conditions:\n- group: \"[\"\n  type: Ready\n  skip: true\n",
+	} {
+		p := filepath.Join(dir, name+".yaml")
+		writeFile(t, p, content)
+		if _, err := LoadRulesFile(p); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestClassifyConditionWithRules(t *testing.T) {
+	args := &Arguments{ConditionRules: []ConditionRule{
+		{Resource: "widgets", Type: "Synced", Healthy: "False"},
+		{Type: "Flaky*", Skip: true},
+		{Ignore: `^pods Ready=False Evicted `},
+		// Never reached for Ready of widgets: the first matching rule wins.
+		{Resource: "widgets", Type: "Ready", Healthy: "True"},
+		{Resource: "widgets", Type: "Ready", Healthy: "False"},
+	}}
+	if err := args.validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		resource, conditionType, status, reason string
+		want                                    string
+	}{
+		{"widgets", "Synced", "False", "", verdictHealthy},
+		{"widgets", "Synced", "True", "", verdictUnhealthy},
+		{"widgets", "Synced", "Unknown", "", verdictUnhealthy},
+		{"gadgets", "Synced", "False", "", verdictUnhealthy},
+		{"gadgets", "FlakyThing", "False", "", verdictSkipped},
+		{"pods", "Ready", "False", "Evicted", verdictSkipped},
+		{"pods", "Ready", "False", "ContainersNotReady", verdictUnhealthy},
+		{"widgets", "Ready", "False", "", verdictUnhealthy},
+		// Without a matching rule, the builtin classification decides.
+		{"pods", "Ready", "True", "", verdictHealthy},
+		{"pods", "DiskPressure", "False", "", verdictHealthy},
+	}
+	for _, tt := range tests {
+		got := args.classifyCondition(schema.GroupVersionResource{Resource: tt.resource}, tt.conditionType, tt.status, tt.reason, "")
+		if got != tt.want {
+			t.Errorf("%s %s=%s %s: got %s, want %s", tt.resource, tt.conditionType, tt.status, tt.reason, got, tt.want)
+		}
+	}
+}
+
+func TestConditionRuleGroup(t *testing.T) {
+	cnpgClusters := schema.GroupVersionResource{Group: "postgresql.cnpg.io", Version: "v1", Resource: "clusters"}
+	capiClusters := schema.GroupVersionResource{Group: "cluster.x-k8s.io", Version: "v1beta1", Resource: "clusters"}
+
+	// learn writes the group, so the rule does not apply to the clusters of
+	// other groups.
+	c := &learnCandidate{gvr: cnpgClusters, conditionType: "Ready", status: "False", reasons: map[string]int{"Setup": 1}}
+	learned, err := c.rule(LearnSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if learned.Group != "postgresql.cnpg.io" {
+		t.Fatalf("expected the group in the learned rule, got %+v", learned)
+	}
+	c = &learnCandidate{gvr: podsGVR, conditionType: "Ready", status: "False", reasons: map[string]int{"Evicted": 1}}
+	if r, err := c.rule(LearnIgnore); err != nil || r.Group != coreGroup {
+		t.Fatalf("expected the core group in the learned rule, got %+v, %v", r, err)
+	}
+
+	args := &Arguments{ConditionRules: []ConditionRule{
+		learned,
+		{Group: coreGroup, Type: "Synced", Healthy: "False"},
+		{Group: "*.x-k8s.io", Resource: "clusters", Type: "Synced", Healthy: "True"},
+	}}
+	if err := args.validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		gvr                   schema.GroupVersionResource
+		conditionType, status string
+		want                  string
+	}{
+		{cnpgClusters, "Ready", "False", verdictSkipped},
+		{capiClusters, "Ready", "False", verdictUnhealthy},
+		{podsGVR, "Synced", "False", verdictHealthy},
+		{widgetsGVR, "Synced", "False", verdictUnhealthy},
+		{capiClusters, "Synced", "True", verdictHealthy},
+		{cnpgClusters, "Synced", "False", verdictUnhealthy},
+	}
+	for _, tt := range tests {
+		got := args.classifyCondition(tt.gvr, tt.conditionType, tt.status, "", "")
+		if got != tt.want {
+			t.Errorf("%s %s=%s: got %s, want %s", tt.gvr.GroupResource(), tt.conditionType, tt.status, got, tt.want)
+		}
+	}
+}
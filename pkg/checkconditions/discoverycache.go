@@ -0,0 +1,53 @@
+package checkconditions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/disk"
+	restclient "k8s.io/client-go/rest"
+)
+
+// diskCachedDiscovery stores the discovery result below cacheDir, so that
+// "forever" and repeated "all" runs don't fetch all API groups every time.
+// The client-go discovery client underneath uses aggregated discovery (two
+// requests for all groups) if the API server supports it.
+type diskCachedDiscovery struct {
+	*disk.CachedDiscoveryClient
+	dir string
+}
+
+var _ discovery.CachedDiscoveryInterface = &diskCachedDiscovery{}
+
+func newDiskCachedDiscovery(config *restclient.Config, cacheKey string, ttl time.Duration) (*diskCachedDiscovery, error) {
+	dir, err := cacheDir(cacheKey)
+	if err != nil {
+		return nil, fmt.Errorf("error finding cache directory: %w", err)
+	}
+	dir = filepath.Join(dir, "discovery")
+	client, err := disk.NewCachedDiscoveryClientForConfig(config, dir, "", ttl)
+	if err != nil {
+		return nil, fmt.Errorf("error creating discovery client: %w", err)
+	}
+	return &diskCachedDiscovery{CachedDiscoveryClient: client, dir: dir}, nil
+}
+
+// Invalidate drops the cached data of this process and removes the files,
+// so that the next process does not use them either.
+func (d *diskCachedDiscovery) Invalidate() {
+	d.CachedDiscoveryClient.Invalidate()
+	if err := os.RemoveAll(d.dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to remove discovery cache %s: %v\n", d.dir, err)
+	}
+}
+
+// invalidateDiscoveryCache is called when a resource type from discovery no
+// longer exists (404 on LIST), for example because its CRD was deleted.
+func invalidateDiscoveryCache(d discovery.DiscoveryInterface) {
+	if cached, ok := d.(discovery.CachedDiscoveryInterface); ok {
+		cached.Invalidate()
+	}
+}
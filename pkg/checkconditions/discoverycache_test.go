@@ -0,0 +1,149 @@
+package checkconditions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	restclient "k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// newDiscoveryServer serves legacy discovery for the core group and
+// example.com/v1. It counts the requests.
+func newDiscoveryServer(t *testing.T, failExampleGroup bool) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	responses := map[string]string{
+		"/api": `{"kind": "APIVersions", "versions": ["v1"]}`,
+		"/apis": `{"kind": "APIGroupList", "apiVersion": "v1", "groups": [{"name": "example.com",
+			"versions": [{"groupVersion": "example.com/v1", "version": "v1"}],
+			"preferredVersion": {"groupVersion": "example.com/v1", "version": "v1"}}]}`,
+		"/api/v1": `{"kind": "APIResourceList", "groupVersion": "v1", "resources": [
+			{"name": "pods", "namespaced": true, "kind": "Pod", "verbs": ["list"]}]}`,
+		"/apis/example.com/v1": `{"kind": "APIResourceList", "groupVersion": "example.com/v1", "resources": [
+			{"name": "widgets", "namespaced": true, "kind": "Widget", "verbs": ["list"]}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if failExampleGroup && r.URL.Path == "/apis/example.com/v1" {
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestDiskCachedDiscoveryIsSharedBetweenRuns(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server, requests := newDiscoveryServer(t, false)
+	config := &restclient.Config{Host: server.URL}
+	key := clusterCacheKey(server.URL)
+
+	preferred := func() int {
+		d, err := newDiskCachedDiscovery(config, key, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lists, err := d.ServerPreferredResources()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lists) != 2 {
+			t.Fatalf("expected 2 group versions, got %d", len(lists))
+		}
+		return int(atomic.SwapInt32(requests, 0))
+	}
+
+	if n := preferred(); n == 0 {
+		t.Fatal("first run should query the API server")
+	}
+	if n := preferred(); n != 0 {
+		t.Fatalf("second run should use the disk cache, got %d requests", n)
+	}
+
+	d, err := newDiskCachedDiscovery(config, key, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalidateDiscoveryCache(d)
+	if _, err := os.Stat(d.dir); !os.IsNotExist(err) {
+		t.Fatalf("cache directory should be removed, got %v", err)
+	}
+	if n := preferred(); n == 0 {
+		t.Fatal("run after invalidation should query the API server")
+	}
+}
+
+func TestDiskCachedDiscoveryKeepsGroupDiscoveryFailures(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	server, _ := newDiscoveryServer(t, true)
+	d, err := newDiskCachedDiscovery(&restclient.Config{Host: server.URL}, clusterCacheKey(server.URL), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lists, err := d.ServerPreferredResources()
+	if !discovery.IsGroupDiscoveryFailedError(err) {
+		t.Fatalf("expected group discovery failure, got %v", err)
+	}
+	if len(lists) != 1 || lists[0].GroupVersion != "v1" {
+		t.Fatalf("expected the core group to be returned anyway, got %v", lists)
+	}
+}
+
+func TestClusterCacheKey(t *testing.T) {
+	for host, want := range map[string]string{
+		"https://10.0.0.1:6443":              "10.0.0.1_6443",
+		"https://example.com/k8s/clusters/c": "example.com_k8s_clusters_c",
+	} {
+		if got := clusterCacheKey(host); got != want {
+			t.Errorf("%s: expected %s, got %s", host, want, got)
+		}
+	}
+}
+
+type invalidatingDiscovery struct {
+	*fakeDiscovery
+	invalidated int
+}
+
+func (d *invalidatingDiscovery) Fresh() bool { return true }
+func (d *invalidatingDiscovery) Invalidate() { d.invalidated++ }
+
+func TestNotFoundOnListInvalidatesDiscoveryCache(t *testing.T) {
+	c := defaultTestCluster()
+	d := &invalidatingDiscovery{fakeDiscovery: c.discovery}
+	c.clients.Discovery = d
+
+	if _, err := RunAndGetCounterWithClients(context.Background(), c.clients, &Arguments{}); err != nil {
+		t.Fatal(err)
+	}
+	if d.invalidated != 0 {
+		t.Fatal("cache should only be invalidated after a 404")
+	}
+
+	c.dynamic.PrependReactor("list", "widgets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewNotFound(widgetsGVR.GroupResource(), "")
+	})
+	if _, err := RunAndGetCounterWithClients(context.Background(), c.clients, &Arguments{}); err != nil {
+		t.Fatal(err)
+	}
+	if d.invalidated != 1 {
+		t.Fatalf("expected one invalidation, got %d", d.invalidated)
+	}
+}
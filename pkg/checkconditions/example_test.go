@@ -0,0 +1,29 @@
+package checkconditions_test
+
+import (
+	"github.com/guettli/check-conditions/cmd"
+	"github.com/guettli/check-conditions/pkg/checkconditions"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// A custom binary registers checkers for its CRDs and reuses the commands
+// of check-conditions unchanged.
+func ExampleRegisterChecker() {
+	checkconditions.RegisterChecker("backup-age", checkconditions.ObjectCheckerFunc(
+		func(gvr schema.GroupVersionResource, obj unstructured.Unstructured) []checkconditions.Finding {
+			if gvr.Group != "backup.example.com" || gvr.Resource != "backups" {
+				return nil
+			}
+			phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+			if phase != "Failed" {
+				return nil
+			}
+			return []checkconditions.Finding{{
+				Type:     "BackupFailed",
+				Text:     "Backup failed",
+				Severity: checkconditions.SeverityError,
+			}}
+		}))
+	cmd.Execute()
+}
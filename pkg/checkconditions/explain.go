@@ -0,0 +1,224 @@
+package checkconditions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// decision is the verdict about a condition and the classification step
+// which decided it, see Arguments.Explain.
+type decision struct {
+	verdict string
+	step    string
+}
+
+// Steps of the decision trail. The steps of the meaning maps, the suffixes
+// and the ignore regexes contain the matching entry, for example
+// "positive suffix Ready".
+const (
+	stepSkipList      = "skip list"
+	stepConditionDone = "conditionDone"
+	stepNoRule        = "no rule matched"
+	// stepReadyDedup drops Ready, if another condition has the same status,
+	// reason and message.
+	stepReadyDedup = "Ready-dedup"
+	// stepMerge joins conditions with the same status, reason and message
+	// into one finding.
+	stepMerge = "merge"
+)
+
+// explain adds a line to the decision trail and counts the hit of the step.
+func (o *handleResourceTypeOutput) explain(gvr schema.GroupVersionResource, obj unstructured.Unstructured, step, format string, a ...any) {
+	if o.ruleHits == nil {
+		o.ruleHits = map[string]int{}
+	}
+	o.ruleHits[step]++
+	o.explanations = append(o.explanations, fmt.Sprintf("  %s %s %s %s", obj.GetNamespace(), gvr.Resource, obj.GetName(), fmt.Sprintf(format, a...)))
+}
+
+// explainConditions adds the decision about each condition of an object to
+// the decision trail.
+func (o *handleResourceTypeOutput) explainConditions(args *Arguments, gvr schema.GroupVersionResource, obj unstructured.Unstructured, conditions []interface{}) {
+	for _, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _ := conditionMap["type"].(string)
+		conditionStatus, _ := conditionMap["status"].(string)
+		conditionReason, _ := conditionMap["reason"].(string)
+		conditionMessage, _ := conditionMap["message"].(string)
+		d := args.explainCondition(gvr, conditionType, conditionStatus, conditionReason, conditionMessage)
+		o.explain(gvr, obj, d.step, "%s: %s (%s)", explainLabel(conditionType, conditionStatus, conditionReason), d.verdict, d.step)
+	}
+}
+
+// explainLabel returns "Type=Status Reason" for the decision trail.
+func explainLabel(conditionType, conditionStatus, conditionReason string) string {
+	label := conditionType + "=" + conditionStatus
+	if conditionReason != "" {
+		label += " " + conditionReason
+	}
+	return label
+}
+
+// objectOfExplanation returns "namespace resource name" of a line of the
+// decision trail. The lines are sorted by object only, so that the
+// conditions keep their order.
+func objectOfExplanation(line string) string {
+	parts := strings.SplitN(strings.TrimPrefix(line, "  "), " ", 4)
+	return strings.Join(parts[:min(3, len(parts))], " ")
+}
+
+// writeExplanations writes the decision trail of a scan.
+func writeExplanations(w io.Writer, counter *Counter) {
+	fmt.Fprintln(w, "Decision trail:")
+	for _, line := range counter.explanations {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// writeRuleHits writes how often each step decided, most frequent first.
+func writeRuleHits(w io.Writer, ruleHits map[string]int) {
+	steps := make([]string, 0, len(ruleHits))
+	for step := range ruleHits {
+		steps = append(steps, step)
+	}
+	slices.SortFunc(steps, func(a, b string) int {
+		if ruleHits[a] != ruleHits[b] {
+			return ruleHits[b] - ruleHits[a]
+		}
+		return strings.Compare(a, b)
+	})
+	fmt.Fprintln(w, "Rule hits:")
+	for _, step := range steps {
+		fmt.Fprintf(w, "  %7d %s\n", ruleHits[step], step)
+	}
+}
+
+// RunExplain explains the conditions of one object of the cluster: which
+// step decided about each condition, and the resulting findings. resource
+// is a resource name like "machines", optionally with group
+// ("machines.cluster.x-k8s.io"). name is "namespace/name", or "name" for
+// cluster-scoped objects and with a single -n namespace.
+func RunExplain(ctx context.Context, args *Arguments, resource, name string, w io.Writer) error {
+	namespace, name, ok := strings.Cut(name, "/")
+	if !ok {
+		name, namespace = namespace, ""
+		if len(args.NamespacePatterns) == 1 && !patternHasGlob(args.NamespacePatterns[0]) {
+			namespace = args.NamespacePatterns[0]
+		}
+	}
+	clients, err := args.clients()
+	if err != nil {
+		return err
+	}
+	serverResources, err := clients.Discovery.ServerPreferredResources()
+	if err != nil && len(serverResources) == 0 {
+		return fmt.Errorf("error getting server resources: %w", err)
+	}
+	gvr, namespaced, err := findResource(serverResources, resource)
+	if err != nil {
+		return err
+	}
+	if !namespaced {
+		namespace = ""
+	} else if namespace == "" {
+		return fmt.Errorf("%s is namespaced, use namespace/name or -n namespace", gvr.GroupResource())
+	}
+	obj, err := clients.Dynamic.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting %s %s: %w", gvr.GroupResource(), name, err)
+	}
+	return explainObjects(args, []resourceList{{gvr: gvr, list: &unstructured.UnstructuredList{Items: []unstructured.Unstructured{*obj}}}}, w)
+}
+
+// RunExplainFiles explains the conditions of the objects in files, see
+// ReadObjects and RunExplain.
+func RunExplainFiles(args *Arguments, paths []string, stdin io.Reader, w io.Writer) error {
+	objects, err := ReadObjects(paths, stdin)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		return errors.New("no objects found")
+	}
+	return explainObjects(args, groupByResource(objects), w)
+}
+
+// explainObjects writes the decision trail and the findings of objects.
+func explainObjects(args *Arguments, lists []resourceList, w io.Writer) error {
+	explain := *args
+	explain.Explain = true
+	if err := explain.validate(); err != nil {
+		return err
+	}
+	if err := explain.loadFiles(); err != nil {
+		return err
+	}
+	counter := Counter{}
+	for _, rl := range lists {
+		counter.add(checkList(&explain, rl.gvr, rl.list, 0))
+	}
+	counter.sort()
+	writeExplanations(w, &counter)
+	if len(counter.Lines) == 0 {
+		fmt.Fprintln(w, "No findings.")
+		return nil
+	}
+	fmt.Fprintln(w, "Findings:")
+	for _, line := range counter.Lines {
+		fmt.Fprintln(w, line)
+	}
+	return nil
+}
+
+// findResource returns the resource type of a name like "machines" or
+// "machines.cluster.x-k8s.io", and whether it is namespaced. Kinds and
+// singular names are accepted, too.
+func findResource(serverResources []*metav1.APIResourceList, name string) (schema.GroupVersionResource, bool, error) {
+	resource, group, _ := strings.Cut(strings.ToLower(name), ".")
+	var found []schema.GroupVersionResource
+	namespaced := map[schema.GroupVersionResource]bool{}
+	for _, list := range serverResources {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		if group != "" && gv.Group != group {
+			continue
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") {
+				// Subresource like pods/log.
+				continue
+			}
+			if r.Name != resource && r.SingularName != resource && strings.ToLower(r.Kind) != resource {
+				continue
+			}
+			gvr := gv.WithResource(r.Name)
+			found = append(found, gvr)
+			namespaced[gvr] = r.Namespaced
+		}
+	}
+	switch len(found) {
+	case 0:
+		return schema.GroupVersionResource{}, false, fmt.Errorf("unknown resource %q", name)
+	case 1:
+		return found[0], namespaced[found[0]], nil
+	}
+	names := make([]string, 0, len(found))
+	for _, gvr := range found {
+		names = append(names, gvr.GroupResource().String())
+	}
+	slices.Sort(names)
+	return schema.GroupVersionResource{}, false, fmt.Errorf("resource %q is ambiguous, use one of: %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,143 @@
+package checkconditions
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestExplainCondition(t *testing.T) {
+	args := &Arguments{ConditionRules: []ConditionRule{{Name: "synced", Resource: "widgets", Type: "Synced", Healthy: "False"}}}
+	if err := args.validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		resource, conditionType, status, reason, message string
+		want                                             decision
+	}{
+		{"widgets", "Synced", "False", "", "", decision{verdictHealthy, "condition rule synced"}},
+		{"pods", "DisruptionAllowed", "False", "", "", decision{verdictSkipped, stepSkipList}},
+		{"machines", "NodeKubeadmLabelsAndTaintsSet", "True", "", "", decision{verdictHealthy, "positive meaning map machines"}},
+		{"deployments", "Progressing", "True", "", "", decision{verdictHealthy, "positive suffix Progressing"}},
+		{"widgets", "CreatedSomething", "True", "", "", decision{verdictHealthy, "positive prefix Created"}},
+		{"machines", "Updating", "False", "", "", decision{verdictHealthy, "negative meaning map machines"}},
+		{"nodes", "DiskPressure", "False", "", "", decision{verdictHealthy, "negative suffix Pressure"}},
+		{"nodes", "FrequentKubeletRestart", "False", "", "", decision{verdictHealthy, "negative prefix Frequent*Restart"}},
+		{"volumes", "Restore", "False", "", "", decision{verdictSkipped, "ignore regex volumes Restore=False"}},
+		{"pods", "Ready", "False", "PodCompleted", "", decision{verdictHealthy, stepConditionDone}},
+		{"pods", "Ready", "False", "ContainersNotReady", "", decision{verdictUnhealthy, stepNoRule}},
+		{"pods", "Ready", "Unknown", "", "", decision{verdictUnhealthy, stepNoRule}},
+	}
+	for _, tt := range tests {
+		got := args.explainCondition(schema.GroupVersionResource{Resource: tt.resource}, tt.conditionType, tt.status, tt.reason, tt.message)
+		if got != tt.want {
+			t.Errorf("%s %s=%s %s: got %+v, want %+v", tt.resource, tt.conditionType, tt.status, tt.reason, got, tt.want)
+		}
+	}
+}
+
+func TestExplainTrail(t *testing.T) {
+	condition := func(conditionType, status, reason, message string) map[string]interface{} {
+		return map[string]interface{}{"type": conditionType, "status": status, "reason": reason, "message": message}
+	}
+	lists := groupByResource([]unstructured.Unstructured{
+		*newTestObject(widgetsGVR, "Widget", "team-a", "widget-a",
+			condition("Ready", "False", "Waiting", "waiting for the database"),
+			condition("DatabaseReady", "False", "Waiting", "waiting for the database"),
+			condition("Failed", "True", "Backoff", "backoff"),
+			condition("FailureTarget", "True", "Backoff", "backoff"),
+			condition("Available", "True", "", "")),
+	})
+	counter, err := checkResourceLists(&Arguments{Explain: true}, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"  team-a widgets widget-a Ready=False Waiting: unhealthy (no rule matched)",
+		"  team-a widgets widget-a DatabaseReady=False Waiting: unhealthy (no rule matched)",
+		"  team-a widgets widget-a Failed=True Backoff: unhealthy (no rule matched)",
+		"  team-a widgets widget-a FailureTarget=True Backoff: unhealthy (no rule matched)",
+		"  team-a widgets widget-a Available=True: healthy (positive suffix Available)",
+		"  team-a widgets widget-a Ready=False Waiting: dropped (Ready-dedup: DatabaseReady has the same status, reason and message)",
+		"  team-a widgets widget-a Failed/FailureTarget=True Backoff: one finding (merge: same status, reason and message)",
+	}
+	if strings.Join(counter.explanations, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\nwant:\n%s", strings.Join(counter.explanations, "\n"), strings.Join(want, "\n"))
+	}
+	for step, n := range map[string]int{stepNoRule: 4, "positive suffix Available": 1, stepReadyDedup: 1, stepMerge: 1} {
+		if counter.ruleHits[step] != n {
+			t.Errorf("%s: got %d hits, want %d", step, counter.ruleHits[step], n)
+		}
+	}
+	if len(counter.Lines) != 2 {
+		t.Errorf("expected 2 findings, got:\n%s", strings.Join(counter.Lines, "\n"))
+	}
+
+	var out bytes.Buffer
+	writeRuleHits(&out, counter.ruleHits)
+	if !strings.HasPrefix(out.String(), "Rule hits:\n        4 no rule matched\n        1 Ready-dedup\n") {
+		t.Errorf("unexpected rule hits:\n%s", out.String())
+	}
+
+	counter, err = checkResourceLists(&Arguments{}, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.explanations != nil || counter.ruleHits != nil {
+		t.Error("expected no decision trail without --explain")
+	}
+}
+
+func TestRunExplain(t *testing.T) {
+	c := defaultTestCluster()
+	var out bytes.Buffer
+	if err := RunExplain(context.Background(), &Arguments{Clients: c.clients}, "pods", "team-a/pod-a", &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"  team-a pods pod-a Ready=False Testing: unhealthy (no rule matched)",
+		"Findings:\n  team-a pods pod-a Condition Ready=False",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := RunExplain(context.Background(), &Arguments{Clients: c.clients, NamespacePatterns: []string{"kube-system"}}, "Pod", "healthy", &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "healthy (positive suffix Ready)") || !strings.Contains(out.String(), "No findings.") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := RunExplain(context.Background(), &Arguments{Clients: c.clients}, "nodes", "node-1", &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "  nodes node-1 Ready=False Testing: unhealthy") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	for _, tt := range []struct{ resource, name, err string }{
+		{"pods", "pod-a", "is namespaced"},
+		{"gadgets", "team-a/gadget", "unknown resource"},
+		{"widgets.example.org", "team-a/widget-a", "unknown resource"},
+		{"widgets.example.com", "team-a/missing", "not found"},
+	} {
+		err := RunExplain(context.Background(), &Arguments{Clients: c.clients}, tt.resource, tt.name, &out)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s %s: expected error %q, got %v", tt.resource, tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestExplainRejectsGraph(t *testing.T) {
+	if err := (&Arguments{Explain: true, Graph: GraphFormats[0]}).validate(); err == nil {
+		t.Error("expected --explain with --graph to be rejected")
+	}
+}
@@ -0,0 +1,211 @@
+package checkconditions
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// StdinPath is the file name which makes ReadObjects read from stdin.
+const StdinPath = "-"
+
+// ReadObjects reads Kubernetes objects from YAML or JSON files. A path may be
+// a file, a directory (read recursively, only *.yaml, *.yml and *.json) or
+// StdinPath. Multi-document YAML and List objects (for example the output of
+// "kubectl get -A -o yaml") are flattened into single objects.
+func ReadObjects(paths []string, stdin io.Reader) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	for _, p := range paths {
+		if p == StdinPath {
+			objs, err := decodeObjects(stdin, "stdin")
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, objs...)
+			continue
+		}
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			// Explicitly named files are read regardless of their extension.
+			if path != p && !isManifestFile(path) {
+				return nil
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			objs, err := decodeObjects(f, path)
+			if err != nil {
+				return err
+			}
+			objects = append(objects, objs...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+func isManifestFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func decodeObjects(r io.Reader, source string) ([]unstructured.Unstructured, error) {
+	var objects []unstructured.Unstructured
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var m map[string]interface{}
+		err := decoder.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error decoding %s: %w", source, err)
+		}
+		if len(m) == 0 {
+			// Empty document, for example between two "---".
+			continue
+		}
+		obj := unstructured.Unstructured{Object: m}
+		if !obj.IsList() {
+			objects = append(objects, obj)
+			continue
+		}
+		err = obj.EachListItem(func(o runtime.Object) error {
+			u, ok := o.(*unstructured.Unstructured)
+			if !ok {
+				return fmt.Errorf("unexpected list item %T", o)
+			}
+			objects = append(objects, *u)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error reading list in %s: %w", source, err)
+		}
+	}
+}
+
+// resourceList is the content of one resource type: either the result of a
+// LIST request or objects which were read from files.
+type resourceList struct {
+	gvr  schema.GroupVersionResource
+	list *unstructured.UnstructuredList
+}
+
+// groupByResource sorts objects into one list per resource type. Without
+// discovery the resource name is guessed from the kind, like kubectl does for
+// unknown types (Machine -> machines).
+func groupByResource(objects []unstructured.Unstructured) []resourceList {
+	byGVR := map[schema.GroupVersionResource]*unstructured.UnstructuredList{}
+	var order []schema.GroupVersionResource
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		if gvk.Kind == "" {
+			continue
+		}
+		gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+		list, ok := byGVR[gvr]
+		if !ok {
+			list = &unstructured.UnstructuredList{}
+			byGVR[gvr] = list
+			order = append(order, gvr)
+		}
+		list.Items = append(list.Items, obj)
+	}
+	lists := make([]resourceList, 0, len(order))
+	for _, gvr := range order {
+		lists = append(lists, resourceList{gvr: gvr, list: byGVR[gvr]})
+	}
+	return lists
+}
+
+// checkResourceLists runs the checks on lists which are already in memory.
+// The namespace filters are applied to the objects, since there is no API
+// server which could filter them.
+func checkResourceLists(args *Arguments, lists []resourceList) (Counter, error) {
+	counter := Counter{StartTime: time.Now()}
+	if err := validatePatterns(args.NamespacePatterns); err != nil {
+		return counter, err
+	}
+	if err := args.validate(); err != nil {
+		return counter, err
+	}
+	if err := args.loadFiles(); err != nil {
+		return counter, err
+	}
+	if args.NamespaceAnnotations {
+		args.namespaceAnnotations = namespaceAnnotationsOf(lists)
+		counter.add(invalidNamespaceAnnotations(args))
+	}
+	namespaces := map[string]struct{}{}
+	for _, rl := range lists {
+		if skipResourceType(rl.gvr) {
+			continue
+		}
+		list := rl.list
+		if args.namespaceFilterActive() {
+			list = &unstructured.UnstructuredList{}
+			for _, obj := range rl.list.Items {
+				ns := obj.GetNamespace()
+				if ns == "" || !matchAnyPattern(ns, args.NamespacePatterns) {
+					continue
+				}
+				namespaces[ns] = struct{}{}
+				list.Items = append(list.Items, obj)
+			}
+			if len(list.Items) == 0 {
+				continue
+			}
+		}
+		counter.add(checkList(args, rl.gvr, list, 0))
+	}
+	if args.namespaceFilterActive() {
+		args.Namespaces = make([]string, 0, len(namespaces))
+		for ns := range namespaces {
+			args.Namespaces = append(args.Namespaces, ns)
+		}
+		slices.Sort(args.Namespaces)
+	}
+	counter.sort()
+	return counter, nil
+}
+
+// RunCheckFiles checks objects read from files, directories or stdin (see
+// ReadObjects) the same way "all" checks a cluster. The output is the same
+// as the output of RunAllOnce. It returns true if an unhealthy condition was
+// found.
+func RunCheckFiles(args *Arguments, paths []string, stdin io.Reader) (bool, error) {
+	objects, err := ReadObjects(paths, stdin)
+	if err != nil {
+		return false, err
+	}
+	counter, err := checkResourceLists(args, groupByResource(objects))
+	if err != nil {
+		return false, err
+	}
+	return printCounter(args, &counter)
+}
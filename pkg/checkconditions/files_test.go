@@ -0,0 +1,182 @@
+package checkconditions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const podsListYAML = `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: pod-a
+    namespace: team-a
+  status:
+    conditions:
+    - type: Ready
+      status: "False"
+      reason: Testing
+      message: set by test
+      lastTransitionTime: "2024-05-01T09:00:00Z"
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: healthy
+    namespace: kube-system
+  status:
+    conditions:
+    - type: Ready
+      status: "True"
+`
+
+const machinesYAML = `---
+apiVersion: cluster.x-k8s.io/v1beta1
+kind: Machine
+metadata:
+  name: machine-1
+  namespace: team-b
+  deletionTimestamp: "2024-05-01T09:30:00Z"
+status:
+  conditions:
+  - type: NodeKubeadmLabelsAndTaintsSet
+    status: "True"
+---
+---
+apiVersion: cluster.x-k8s.io/v1beta1
+kind: Machine
+metadata:
+  name: machine-2
+  namespace: team-b
+status:
+  conditions:
+  - type: InfrastructureReady
+    status: "False"
+    reason: WaitingForInfrastructure
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadObjectsDirectoryListsAndStdin(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pods.yaml"), podsListYAML)
+	writeFile(t, filepath.Join(dir, "sub", "machines.yml"), machinesYAML)
+	writeFile(t, filepath.Join(dir, "README.md"), "not a manifest")
+
+	stdin := strings.NewReader(`{"apiVersion": "v1", "kind": "Node", "metadata": {"name": "node-1"}}`)
+	objects, err := ReadObjects([]string{dir, StdinPath}, stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, o := range objects {
+		names = append(names, o.GetName())
+	}
+	want := []string{"pod-a", "healthy", "machine-1", "machine-2", "node-1"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+}
+
+func TestReadObjectsInvalidYAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.yaml")
+	writeFile(t, path, "kind: [unclosed")
+	_, err := ReadObjects([]string{path}, nil)
+	if err == nil || !strings.Contains(err.Error(), "broken.yaml") {
+		t.Fatalf("expected decode error naming the file, got %v", err)
+	}
+}
+
+func TestCheckResourceListsUsesNow(t *testing.T) {
+	objects, err := ReadObjects([]string{StdinPath}, strings.NewReader(podsListYAML+"---\n"+machinesYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := &Arguments{
+		Now:                            time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		WarnDeletionTimestampOlderThan: 10 * time.Minute,
+	}
+	counter, err := checkResourceLists(args, groupByResource(objects))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`  team-a pods pod-a Condition Ready=False Testing "set by test" (1h0m0s) [warning]`,
+		`  team-b machines machine-1 DeletionTimestamp set for 30m0s [warning]`,
+		`  team-b machines machine-2 Condition InfrastructureReady=False WaitingForInfrastructure "" () [warning]`,
+	}
+	if !slices.Equal(counter.Lines, want) {
+		t.Fatalf("unexpected lines:\n%s", strings.Join(counter.Lines, "\n"))
+	}
+	if counter.CheckedResourceTypes != 2 || counter.CheckedResources != 4 {
+		t.Errorf("unexpected counts: %d types, %d resources", counter.CheckedResourceTypes, counter.CheckedResources)
+	}
+}
+
+func TestCheckResourceListsNamespaceFilter(t *testing.T) {
+	objects, err := ReadObjects([]string{StdinPath}, strings.NewReader(podsListYAML+"---\n"+machinesYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := &Arguments{
+		NamespacePatterns:        []string{"team-*", "kube-system"},
+		ExcludeNamespacePatterns: []string{"team-b"},
+	}
+	counter, err := checkResourceLists(args, groupByResource(objects))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.Lines) != 1 || !strings.Contains(counter.Lines[0], "pod-a") {
+		t.Fatalf("expected only pod-a, got %v", counter.Lines)
+	}
+	if got := strings.Join(args.Namespaces, ","); got != "kube-system,team-a,team-b" {
+		t.Errorf("unexpected namespaces %q", got)
+	}
+}
+
+// Checking a dump must give the same lines as checking the cluster.
+func TestCheckResourceListsMatchesClusterScan(t *testing.T) {
+	c := defaultTestCluster()
+	clusterCounter, err := RunAndGetCounterWithClients(context.Background(), c.clients, &Arguments{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var objects []unstructured.Unstructured
+	for _, gvr := range []schema.GroupVersionResource{podsGVR, nodesGVR, widgetsGVR} {
+		list, err := c.dynamic.Resource(gvr).List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, list.Items...)
+	}
+	fileCounter, err := checkResourceLists(&Arguments{}, groupByResource(objects))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(clusterCounter.Lines, fileCounter.Lines) {
+		t.Fatalf("cluster:\n%s\nfiles:\n%s", strings.Join(clusterCounter.Lines, "\n"), strings.Join(fileCounter.Lines, "\n"))
+	}
+	if clusterCounter.CheckedConditions != fileCounter.CheckedConditions {
+		t.Errorf("checked conditions differ: %d vs %d", clusterCounter.CheckedConditions, fileCounter.CheckedConditions)
+	}
+}
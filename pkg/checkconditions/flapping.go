@@ -0,0 +1,124 @@
+package checkconditions
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// flappingType is the type of findings about conditions which change their
+// status often, see Arguments.FlapThreshold.
+const flappingType = "Flapping"
+
+// flapKey identifies a condition of an object.
+type flapKey struct {
+	gvr           schema.GroupVersionResource
+	uid           types.UID
+	namespace     string
+	name          string
+	conditionType string
+}
+
+// flapHistory is what the flapTracker knows about a condition.
+type flapHistory struct {
+	status             string
+	lastTransitionTime time.Time
+	// transitions seen in the window, oldest first.
+	transitions []time.Time
+	lastSeen    time.Time
+}
+
+// flapTracker remembers the conditions of all objects between the
+// iterations of "forever" and "while". A condition flaps if it changed more
+// often than Arguments.FlapThreshold within Arguments.FlapWindow, even if it
+// is healthy right now.
+type flapTracker struct {
+	mu      sync.Mutex
+	history map[flapKey]*flapHistory
+}
+
+func newFlapTracker() *flapTracker {
+	return &flapTracker{history: map[flapKey]*flapHistory{}}
+}
+
+// startFlapDetection enables flapping detection for the following scans, if
+// Arguments.FlapThreshold is set. It is called by RunForever and
+// RunWhileRegex, a single scan can't see transitions.
+func (a *Arguments) startFlapDetection() {
+	if a.FlapThreshold > 0 && a.flapping == nil {
+		a.flapping = newFlapTracker()
+	}
+}
+
+// observe records the conditions of an object and returns a finding for each
+// condition which flaps.
+func (t *flapTracker) observe(args *Arguments, gvr schema.GroupVersionResource, obj unstructured.Unstructured, conditions []interface{}) []Finding {
+	now := args.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var findings []Finding
+	for _, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _ := conditionMap["type"].(string)
+		status, _ := conditionMap["status"].(string)
+		if conditionType == "" {
+			continue
+		}
+		var ltt time.Time
+		if s, _ := conditionMap["lastTransitionTime"].(string); s != "" {
+			ltt, _ = time.Parse(time.RFC3339, s)
+		}
+		key := flapKey{gvr, obj.GetUID(), obj.GetNamespace(), obj.GetName(), conditionType}
+		h, ok := t.history[key]
+		if !ok {
+			t.history[key] = &flapHistory{status: status, lastTransitionTime: ltt, lastSeen: now}
+			continue
+		}
+		h.lastSeen = now
+		if status != h.status || ltt.After(h.lastTransitionTime) {
+			// Several transitions between two scans are seen as one. The
+			// lastTransitionTime is more exact than the time of the scan.
+			at := now
+			if ltt.After(h.lastTransitionTime) && !ltt.After(now) {
+				at = ltt
+			}
+			h.transitions = append(h.transitions, at)
+			h.status = status
+			h.lastTransitionTime = ltt
+		}
+		i := 0
+		for i < len(h.transitions) && now.Sub(h.transitions[i]) > args.FlapWindow {
+			i++
+		}
+		h.transitions = h.transitions[i:]
+		if len(h.transitions) < args.FlapThreshold {
+			continue
+		}
+		f := newFinding(gvr, obj, fmt.Sprintf("Flapping: condition %s changed %d times within %s, now %s",
+			conditionType, len(h.transitions), args.FlapWindow, status))
+		f.Type = flappingType
+		f.Reason = conditionType
+		f.LastTransitionTime = h.transitions[0]
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// prune forgets conditions which were not seen within the window, for
+// example because the object was deleted.
+func (t *flapTracker) prune(now time.Time, window time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key, h := range t.history {
+		if now.Sub(h.lastSeen) > window {
+			delete(t.history, key)
+		}
+	}
+}
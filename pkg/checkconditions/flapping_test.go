@@ -0,0 +1,59 @@
+package checkconditions
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlapping(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cluster := defaultTestCluster()
+	args := &Arguments{Now: now, FlapThreshold: 3, FlapWindow: 10 * time.Minute}
+	args.startFlapDetection()
+	scan := func(status string) Counter {
+		t.Helper()
+		c := readyCondition(status)
+		c["lastTransitionTime"] = args.Now.Format(time.RFC3339)
+		if err := cluster.dynamic.Tracker().Update(podsGVR,
+			newTestObject(podsGVR, "Pod", "kube-system", "healthy", c), "kube-system"); err != nil {
+			t.Fatal(err)
+		}
+		counter, err := RunAndGetCounterWithClients(context.Background(), cluster.clients, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args.Now = args.Now.Add(time.Minute)
+		return counter
+	}
+	for i, status := range []string{"False", "True", "False"} {
+		if lines := linesContaining(scan(status).Lines, "Flapping"); len(lines) != 0 {
+			t.Fatalf("scan %d: unexpected flapping %v", i, lines)
+		}
+	}
+	// The third transition within the window, healthy right now.
+	lines := linesContaining(scan("True").Lines, "Flapping")
+	if len(lines) != 1 || !strings.Contains(lines[0], "kube-system pods healthy Flapping: condition Ready changed 3 times within 10m0s, now True [warning]") {
+		t.Fatalf("expected one flapping finding, got %v", lines)
+	}
+
+	// Without transitions the old ones leave the window.
+	args.Now = args.Now.Add(10 * time.Minute)
+	if lines := linesContaining(scan("True").Lines, "Flapping"); len(lines) != 0 {
+		t.Errorf("expected flapping to end, got %v", lines)
+	}
+}
+
+func TestFlapTrackerPrune(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	args := &Arguments{Now: now, FlapThreshold: 1, FlapWindow: time.Minute}
+	tracker := newFlapTracker()
+	obj := newTestObject(podsGVR, "Pod", "ns", "p", readyCondition("True"))
+	conditions, _, _ := conditionsOf(podsGVR, *obj)
+	tracker.observe(args, podsGVR, *obj, conditions)
+	tracker.prune(now.Add(2*time.Minute), args.FlapWindow)
+	if len(tracker.history) != 0 {
+		t.Errorf("expected the history to be pruned, got %v", tracker.history)
+	}
+}
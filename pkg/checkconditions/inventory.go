@@ -0,0 +1,201 @@
+package checkconditions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/exp/slices"
+)
+
+// InventoryEntry counts the conditions of one type with one status, for
+// example all Ready=False conditions of pods.
+type InventoryEntry struct {
+	Group    string `json:"group"`
+	Resource string `json:"resource"`
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	Count    int    `json:"count"`
+	// Verdicts counts how the current rules treat the conditions: healthy,
+	// unhealthy or skipped. Usually there is only one verdict, but the
+	// ignore regexes and conditionDone depend on reason and message.
+	Verdicts   map[string]int `json:"verdicts"`
+	TopReasons []ReasonCount  `json:"topReasons"`
+}
+
+type ReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// Output formats of the inventory.
+const (
+	InventoryTable = "table"
+	InventoryJSON  = "json"
+)
+
+// RunInventory scans the cluster, or a snapshot if snapshotPath is set, and
+// writes the inventory of all conditions to stdout. Nothing is filtered by
+// the rules, the inventory shows what the rules would do.
+func RunInventory(ctx context.Context, args *Arguments, snapshotPath, format string, topReasons int) error {
+	if format != InventoryTable && format != InventoryJSON {
+		return fmt.Errorf("unknown output format %q, use %q or %q", format, InventoryTable, InventoryJSON)
+	}
+	if err := args.rejectOutputFlags("inventory"); err != nil {
+		return err
+	}
+	var lists []resourceList
+	if snapshotPath != "" {
+		f, err := os.Open(snapshotPath)
+		if err != nil {
+			return fmt.Errorf("error opening snapshot: %w", err)
+		}
+		defer f.Close()
+		_, lists, err = readSnapshot(f)
+		if err != nil {
+			return fmt.Errorf("error reading snapshot %s: %w", snapshotPath, err)
+		}
+	} else {
+		clients, err := args.clients()
+		if err != nil {
+			return err
+		}
+		args.keepLists = true
+		defer func() { args.keepLists = false }()
+		if format == InventoryJSON {
+			// Keep stdout valid JSON.
+			args.messageWriter = os.Stderr
+			defer func() { args.messageWriter = nil }()
+		}
+		counter, err := runWithRetries(ctx, clients, args)
+		if err != nil {
+			return err
+		}
+		writeListErrors(os.Stderr, counter.ListErrors)
+		lists = counter.lists
+	}
+	return writeInventory(os.Stdout, buildInventory(args, lists, topReasons), format)
+}
+
+// buildInventory counts the conditions of all objects in scope, per
+// resource type, condition type and status.
+func buildInventory(args *Arguments, lists []resourceList, topReasons int) []InventoryEntry {
+	type key struct {
+		group, resource, conditionType, status string
+	}
+	entries := map[key]*InventoryEntry{}
+	reasons := map[key]map[string]int{}
+	inScope := args.namespaceInScope()
+	for _, rl := range lists {
+		for _, obj := range rl.list.Items {
+			if !inScope(obj.GetNamespace()) {
+				continue
+			}
+			conditions, _, _ := conditionsOf(rl.gvr, obj)
+			for _, condition := range conditions {
+				conditionMap, ok := condition.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				conditionType, _ := conditionMap["type"].(string)
+				conditionStatus, _ := conditionMap["status"].(string)
+				conditionReason, _ := conditionMap["reason"].(string)
+				conditionMessage, _ := conditionMap["message"].(string)
+				k := key{rl.gvr.Group, rl.gvr.Resource, conditionType, conditionStatus}
+				e := entries[k]
+				if e == nil {
+					e = &InventoryEntry{
+						Group: k.group, Resource: k.resource, Type: k.conditionType, Status: k.status,
+						Verdicts: map[string]int{},
+					}
+					entries[k] = e
+					reasons[k] = map[string]int{}
+				}
+				e.Count++
+				e.Verdicts[args.classifyCondition(rl.gvr, conditionType, conditionStatus, conditionReason, conditionMessage)]++
+				reasons[k][conditionReason]++
+			}
+		}
+	}
+	result := make([]InventoryEntry, 0, len(entries))
+	for k, e := range entries {
+		e.TopReasons = topReasonCounts(reasons[k], topReasons)
+		result = append(result, *e)
+	}
+	slices.SortFunc(result, func(a, b InventoryEntry) int {
+		for _, c := range [][2]string{
+			{a.Group, b.Group}, {a.Resource, b.Resource}, {a.Type, b.Type}, {a.Status, b.Status},
+		} {
+			if n := strings.Compare(c[0], c[1]); n != 0 {
+				return n
+			}
+		}
+		return 0
+	})
+	return result
+}
+
+// topReasonCounts returns the n most frequent reasons, most frequent first.
+// n <= 0 means all.
+func topReasonCounts(reasons map[string]int, n int) []ReasonCount {
+	counts := make([]ReasonCount, 0, len(reasons))
+	for reason, count := range reasons {
+		counts = append(counts, ReasonCount{reason, count})
+	}
+	slices.SortFunc(counts, func(a, b ReasonCount) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Reason, b.Reason)
+	})
+	if n > 0 && len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+func writeInventory(w io.Writer, entries []InventoryEntry, format string) error {
+	if format == InventoryJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tRESOURCE\tTYPE\tSTATUS\tCOUNT\tRULES\tTOP REASONS")
+	for _, e := range entries {
+		group := e.Group
+		if group == "" {
+			group = "core"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			group, e.Resource, e.Type, e.Status, e.Count, verdictsString(e.Verdicts), reasonsString(e.TopReasons))
+	}
+	return tw.Flush()
+}
+
+// verdictsString returns the verdict, or all verdicts with their counts if
+// the rules treat the conditions differently, most frequent first.
+func verdictsString(verdicts map[string]int) string {
+	if len(verdicts) == 1 {
+		for v := range verdicts {
+			return v
+		}
+	}
+	return reasonsString(topReasonCounts(verdicts, 0))
+}
+
+func reasonsString(counts []ReasonCount) string {
+	parts := make([]string, 0, len(counts))
+	for _, c := range counts {
+		reason := c.Reason
+		if reason == "" {
+			reason = `""`
+		}
+		parts = append(parts, fmt.Sprintf("%s(%d)", reason, c.Count))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,118 @@
+package checkconditions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func inventoryTestLists() []resourceList {
+	condition := func(conditionType, status, reason string) map[string]interface{} {
+		return map[string]interface{}{"type": conditionType, "status": status, "reason": reason}
+	}
+	return groupByResource([]unstructured.Unstructured{
+		*newTestObject(podsGVR, "Pod", "team-a", "a", condition("Ready", "False", "ContainersNotReady"),
+			condition("PodReadyToStartContainers", "False", "")),
+		*newTestObject(podsGVR, "Pod", "team-a", "b", condition("Ready", "False", "ContainersNotReady")),
+		*newTestObject(podsGVR, "Pod", "team-b", "c", condition("Ready", "False", "PodCompleted")),
+		*newTestObject(podsGVR, "Pod", "team-b", "d", condition("Ready", "True", "")),
+		*newTestObject(podsGVR, "Pod", "kube-system", "e", condition("Ready", "False", "Evicted")),
+		*newTestObject(widgetsGVR, "Widget", "team-a", "w", condition("DiskPressure", "False", "")),
+	})
+}
+
+func TestBuildInventory(t *testing.T) {
+	entries := buildInventory(&Arguments{}, inventoryTestLists(), 2)
+	var got []string
+	for _, e := range entries {
+		got = append(got, strings.Join([]string{e.Group, e.Resource, e.Type, e.Status, verdictsString(e.Verdicts), reasonsString(e.TopReasons)}, " "))
+	}
+	want := []string{
+		" pods PodReadyToStartContainers False skipped \"\"(1)",
+		" pods Ready False unhealthy(3) healthy(1) ContainersNotReady(2) Evicted(1)",
+		" pods Ready True healthy \"\"(1)",
+		"example.com widgets DiskPressure False healthy \"\"(1)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if entries[1].Count != 4 {
+		t.Errorf("expected 4 Ready=False pods, got %d", entries[1].Count)
+	}
+}
+
+func TestBuildInventoryNamespaceFilter(t *testing.T) {
+	entries := buildInventory(&Arguments{ExcludeNamespacePatterns: []string{"kube-*"}}, inventoryTestLists(), 0)
+	for _, e := range entries {
+		if e.Type == "Ready" && e.Status == "False" {
+			if e.Count != 3 {
+				t.Errorf("expected kube-system to be excluded, got %d Ready=False pods", e.Count)
+			}
+			return
+		}
+	}
+	t.Fatal("Ready=False of pods missing")
+}
+
+func TestWriteInventory(t *testing.T) {
+	entries := buildInventory(&Arguments{}, inventoryTestLists(), 3)
+
+	var table bytes.Buffer
+	if err := writeInventory(&table, entries, InventoryTable); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "GROUP") {
+		t.Fatalf("expected a header and 4 rows:\n%s", table.String())
+	}
+	if fields := strings.Fields(lines[2]); fields[0] != "core" || fields[4] != "4" {
+		t.Errorf("unexpected row %q", lines[2])
+	}
+
+	var out bytes.Buffer
+	if err := writeInventory(&out, entries, InventoryJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []InventoryEntry
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 4 || decoded[1].Verdicts[verdictHealthy] != 1 || decoded[1].TopReasons[0].Reason != "ContainersNotReady" {
+		t.Errorf("unexpected JSON:\n%s", out.String())
+	}
+}
+
+func TestRunInventoryRejectsUnknownFormat(t *testing.T) {
+	err := RunInventory(context.Background(), &Arguments{}, "", "yaml", 3)
+	if err == nil || !strings.Contains(err.Error(), "unknown output format") {
+		t.Fatalf("expected an error about the format, got %v", err)
+	}
+}
+
+func TestRunInventoryJSONStdout(t *testing.T) {
+	for _, args := range []*Arguments{{Stream: true}, {Priority: true}, {Graph: "dot"}, {GroupBy: GroupByOwner}, {BaselineFile: "baseline.json"}} {
+		if err := RunInventory(context.Background(), args, "", InventoryJSON, 3); err == nil || !strings.Contains(err.Error(), "can't be combined") {
+			t.Errorf("%+v: expected an error, got %v", args, err)
+		}
+	}
+
+	// The messages of the scan go to stderr.
+	c := defaultTestCluster()
+	args := &Arguments{Clients: c.clients, Verbose: true}
+	out := captureStdout(t, func() {
+		if err := RunInventory(context.Background(), args, "", InventoryJSON, 3); err != nil {
+			t.Fatal(err)
+		}
+	})
+	var entries []InventoryEntry
+	if err := json.Unmarshal([]byte(out), &entries); err != nil || len(entries) == 0 {
+		t.Fatalf("stdout is not the inventory JSON: %v\n%s", err, out)
+	}
+	if args.messageWriter != nil {
+		t.Error("the message writer should be reset")
+	}
+}
@@ -0,0 +1,265 @@
+package checkconditions
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Answers of the "learn" sub-command.
+const (
+	// LearnHealthy makes the current status the healthy one of the
+	// condition type (polarity).
+	LearnHealthy = "healthy"
+	// LearnSkip skips the condition type, both statuses are fine.
+	LearnSkip = "skip"
+	// LearnIgnore ignores the current reasons of the condition type and
+	// status. Other reasons are still reported.
+	LearnIgnore = "ignore"
+)
+
+// LearnAnswers are the answers which --accept-all supports.
+var LearnAnswers = []string{LearnHealthy, LearnSkip, LearnIgnore}
+
+type LearnOptions struct {
+	// AcceptAll answers AcceptAs for each condition, without asking.
+	AcceptAll bool
+	AcceptAs  string
+	// Resources are glob patterns for the resource types, for example
+	// "widgets" or "*.example.com". Empty means all.
+	Resources []string
+}
+
+// learnCandidate is a condition type and status which the current rules
+// treat as unhealthy, for example Synced=False of widgets.
+type learnCandidate struct {
+	gvr           schema.GroupVersionResource
+	conditionType string
+	status        string
+	count         int
+	reasons       map[string]int
+	// example is namespace/name of the first object.
+	example string
+}
+
+func (c *learnCandidate) String() string {
+	return fmt.Sprintf("%s %s=%s", c.gvr.GroupResource(), c.conditionType, c.status)
+}
+
+// rule returns the condition rule for an answer, or an error if the answer
+// does not fit the status.
+func (c *learnCandidate) rule(answer string) (ConditionRule, error) {
+	r := ConditionRule{Name: c.String(), Group: groupOfRule(c.gvr.Group), Resource: c.gvr.Resource, Type: c.conditionType}
+	switch answer {
+	case LearnHealthy:
+		if c.status != "True" && c.status != "False" {
+			return r, fmt.Errorf("%s can't be the healthy status", c.status)
+		}
+		r.Healthy = c.status
+	case LearnSkip:
+		r.Skip = true
+	case LearnIgnore:
+		reasons := make([]string, 0, len(c.reasons))
+		for reason := range c.reasons {
+			reasons = append(reasons, regexp.QuoteMeta(reason))
+		}
+		slices.Sort(reasons)
+		r.Ignore = fmt.Sprintf("^%s (%s) ",
+			regexp.QuoteMeta(fmt.Sprintf("%s %s=%s", c.gvr.Resource, c.conditionType, c.status)),
+			strings.Join(reasons, "|"))
+	default:
+		return r, fmt.Errorf("unknown answer %q, supported: %s", answer, strings.Join(LearnAnswers, ", "))
+	}
+	return r, r.compile()
+}
+
+// learnCandidates returns the unhealthy condition types and statuses of the
+// objects in scope, sorted by resource type and condition type.
+func learnCandidates(args *Arguments, lists []resourceList, resources []string) []*learnCandidate {
+	type key struct {
+		gvr                   schema.GroupVersionResource
+		conditionType, status string
+	}
+	candidates := map[key]*learnCandidate{}
+	inScope := args.namespaceInScope()
+	for _, rl := range lists {
+		if len(resources) > 0 && !matchAnyPattern(rl.gvr.Resource, resources) &&
+			!matchAnyPattern(rl.gvr.GroupResource().String(), resources) {
+			continue
+		}
+		for _, obj := range rl.list.Items {
+			if !inScope(obj.GetNamespace()) {
+				continue
+			}
+			conditions, _, _ := conditionsOf(rl.gvr, obj)
+			for _, condition := range conditions {
+				conditionMap, ok := condition.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				conditionType, _ := conditionMap["type"].(string)
+				conditionStatus, _ := conditionMap["status"].(string)
+				conditionReason, _ := conditionMap["reason"].(string)
+				conditionMessage, _ := conditionMap["message"].(string)
+				if args.classifyCondition(rl.gvr, conditionType, conditionStatus, conditionReason, conditionMessage) != verdictUnhealthy {
+					continue
+				}
+				k := key{rl.gvr, conditionType, conditionStatus}
+				c := candidates[k]
+				if c == nil {
+					c = &learnCandidate{
+						gvr: rl.gvr, conditionType: conditionType, status: conditionStatus,
+						reasons: map[string]int{}, example: obj.GetName(),
+					}
+					if obj.GetNamespace() != "" {
+						c.example = obj.GetNamespace() + "/" + obj.GetName()
+					}
+					candidates[k] = c
+				}
+				c.count++
+				c.reasons[conditionReason]++
+			}
+		}
+	}
+	result := make([]*learnCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		result = append(result, c)
+	}
+	slices.SortFunc(result, func(a, b *learnCandidate) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return result
+}
+
+// errLearnQuit is returned by askLearn if the user wants to stop.
+var errLearnQuit = errors.New("quit")
+
+// askLearn asks for the answer for one candidate. An empty answer means no
+// rule, the condition is a real problem.
+func askLearn(in *bufio.Reader, out io.Writer, c *learnCandidate) (string, error) {
+	fmt.Fprintf(out, "\n%s: %d objects, for example %s\n", c, c.count, c.example)
+	fmt.Fprintf(out, "  reasons: %s\n", reasonsString(topReasonCounts(c.reasons, 5)))
+	for {
+		fmt.Fprintf(out, "  [h]ealthy (%s is fine), [s]kip %s, [i]gnore these reasons, [n]o rule, [q]uit? [n] ",
+			c.status, c.conditionType)
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			if errors.Is(err, io.EOF) {
+				return "", errLearnQuit
+			}
+			return "", err
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "h", LearnHealthy:
+			return LearnHealthy, nil
+		case "s", LearnSkip:
+			return LearnSkip, nil
+		case "i", LearnIgnore:
+			return LearnIgnore, nil
+		case "", "n", "no":
+			return "", nil
+		case "q", "quit":
+			return "", errLearnQuit
+		}
+	}
+}
+
+// RunLearn scans the cluster and creates condition rules for the unhealthy
+// conditions, so that the findings which are fine disappear. The rules are
+// appended to the rules file at path, which is created if needed. Its
+// existing rules apply to the scan, so conditions are not asked twice.
+// Questions are read from in, unless opts.AcceptAll is set.
+func RunLearn(ctx context.Context, args *Arguments, path string, opts LearnOptions, in io.Reader, out io.Writer) error {
+	if opts.AcceptAll && !slices.Contains(LearnAnswers, opts.AcceptAs) {
+		return fmt.Errorf("invalid --accept-as %q, supported: %s", opts.AcceptAs, strings.Join(LearnAnswers, ", "))
+	}
+	if err := validatePatterns(opts.Resources); err != nil {
+		return err
+	}
+	file := &RulesFile{}
+	if _, err := os.Stat(path); err == nil {
+		if file, err = LoadRulesFile(path); err != nil {
+			return err
+		}
+	}
+	args.ConditionRules = append(slices.Clone(args.ConditionRules), file.Conditions...)
+
+	clients, err := args.clients()
+	if err != nil {
+		return err
+	}
+	args.keepLists = true
+	defer func() { args.keepLists = false }()
+	counter, err := runWithRetries(ctx, clients, args)
+	if err != nil {
+		return err
+	}
+	writeListErrors(out, counter.ListErrors)
+
+	candidates := learnCandidates(args, counter.lists, opts.Resources)
+	if len(candidates) == 0 {
+		fmt.Fprintln(out, "No unhealthy conditions, nothing to learn.")
+		return nil
+	}
+	fmt.Fprintf(out, "%d unhealthy condition types.\n", len(candidates))
+	reader := bufio.NewReader(in)
+	added := 0
+	for _, c := range candidates {
+		answer := opts.AcceptAs
+		if !opts.AcceptAll {
+			answer, err = askLearn(reader, out, c)
+			if errors.Is(err, errLearnQuit) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if answer == "" {
+			continue
+		}
+		r, err := c.rule(answer)
+		if err != nil {
+			fmt.Fprintf(out, "No rule for %s: %v\n", c, err)
+			continue
+		}
+		file.Conditions = append(file.Conditions, r)
+		added++
+	}
+	if added == 0 {
+		fmt.Fprintln(out, "No new rules.")
+		return nil
+	}
+	if err := writeRulesFile(path, file); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Added %d rules to %s. Use them with: check-conditions all --rules %s\n", added, path, path)
+	return nil
+}
+
+// writeRulesFile writes a rules file. Like a snapshot, it is written to a
+// temporary file first.
+func writeRulesFile(path string, file *RulesFile) error {
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("error writing rules %s: %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("error writing rules %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing rules %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package checkconditions
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLearnInteractive(t *testing.T) {
+	c := defaultTestCluster()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	writeFile(t, path, "rules:\n- type: Ready\n  severity: error\n")
+
+	// Candidates are sorted: nodes, pods, widgets. "x" is asked again.
+	var out bytes.Buffer
+	err := RunLearn(context.Background(), &Arguments{Clients: c.clients}, path, LearnOptions{},
+		strings.NewReader("x\nn\ni\nh\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Added 2 rules") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	file, err := LoadRulesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Rules) != 1 {
+		t.Errorf("expected the severity rule to be kept, got %+v", file.Rules)
+	}
+	if len(file.Conditions) != 2 || file.Conditions[0].Ignore == "" || file.Conditions[1].Healthy != "False" {
+		t.Fatalf("unexpected condition rules %+v", file.Conditions)
+	}
+
+	args := &Arguments{ConditionRules: file.Conditions}
+	counter, err := RunAndGetCounterWithClients(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.Lines) != 1 || !strings.Contains(counter.Lines[0], "node-1") {
+		t.Fatalf("expected only the node to be unhealthy, got:\n%s", strings.Join(counter.Lines, "\n"))
+	}
+
+	// The rules of the file apply, so only the node is asked again. EOF quits.
+	out.Reset()
+	err = RunLearn(context.Background(), &Arguments{Clients: c.clients}, path, LearnOptions{}, strings.NewReader(""), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "1 unhealthy condition types") || !strings.Contains(out.String(), "No new rules") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestLearnAcceptAll(t *testing.T) {
+	c := defaultTestCluster()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	var out bytes.Buffer
+	opts := LearnOptions{AcceptAll: true, AcceptAs: LearnSkip, Resources: []string{"*.example.com"}}
+	if err := RunLearn(context.Background(), &Arguments{Clients: c.clients}, path, opts, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	file, err := LoadRulesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Conditions) != 1 || file.Conditions[0].Resource != "widgets" || !file.Conditions[0].Skip {
+		t.Fatalf("unexpected condition rules %+v", file.Conditions)
+	}
+
+	opts = LearnOptions{AcceptAll: true, AcceptAs: "maybe"}
+	if err := RunLearn(context.Background(), &Arguments{Clients: c.clients}, path, opts, nil, &out); err == nil {
+		t.Error("expected an error for an unknown answer")
+	}
+}
+
+func TestLearnCandidateRule(t *testing.T) {
+	c := &learnCandidate{gvr: podsGVR, conditionType: "Ready", status: "Unknown", reasons: map[string]int{"": 1, "Node.Lost": 2}}
+	if _, err := c.rule(LearnHealthy); err == nil {
+		t.Error("Unknown can't be the healthy status")
+	}
+	r, err := c.rule(LearnIgnore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for line, want := range map[string]bool{
+		`pods Ready=Unknown  "msg"`:          true,
+		`pods Ready=Unknown Node.Lost "msg"`: true,
+		`pods Ready=Unknown NodeXLost "msg"`: false,
+		`pods Ready=False Node.Lost "msg"`:   false,
+	} {
+		if got := r.ignore.MatchString(line); got != want {
+			t.Errorf("%s: got %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestWriteRulesFileReplaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := writeRulesFile(path, &RulesFile{Conditions: []ConditionRule{{Type: "Synced", Skip: true}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package checkconditions
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// lintConditionType is the type of findings about conditions which violate
+// the API conventions, see Arguments.LintConditions. The reason tells what is
+// wrong, for example "InvalidStatus".
+const lintConditionType = "ConditionLint"
+
+// camelCaseReason is the pattern of metav1.Condition.Reason, but starting
+// with an upper case letter like the API conventions demand.
+var camelCaseReason = regexp.MustCompile(`^[A-Z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$`)
+
+// lintConditions checks the conditions of an object against the API
+// conventions:
+// https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md#typical-status-properties
+// Conditions which are not a map are reported by printConditions, even
+// without linting.
+func lintConditions(gvr schema.GroupVersionResource, obj unstructured.Unstructured, conditions []interface{}) []Finding {
+	var findings []Finding
+	add := func(conditionType, reason, format string, a ...interface{}) {
+		f := newFinding(gvr, obj, "Lint condition "+conditionType+": "+fmt.Sprintf(format, a...))
+		f.Type = lintConditionType
+		f.Reason = reason
+		findings = append(findings, f)
+	}
+	seen := map[string]bool{}
+	for i, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _ := conditionMap["type"].(string)
+		name := conditionType
+		if conditionType == "" {
+			name = fmt.Sprintf("#%d", i+1)
+			add(name, "MissingType", "type is missing")
+		} else if seen[conditionType] {
+			add(name, "DuplicateType", "type is used more than once")
+		}
+		seen[conditionType] = true
+
+		switch status, _ := conditionMap["status"].(string); status {
+		case "True", "False", "Unknown":
+		case "":
+			add(name, "MissingStatus", "status is missing")
+		default:
+			add(name, "InvalidStatus", "status %q is not True, False or Unknown", status)
+		}
+
+		switch reason, _ := conditionMap["reason"].(string); {
+		case reason == "":
+			add(name, "MissingReason", "reason is missing")
+		case !camelCaseReason.MatchString(reason):
+			add(name, "ReasonNotCamelCase", "reason %q is not CamelCase", reason)
+		}
+
+		switch ltt, _ := conditionMap["lastTransitionTime"].(string); {
+		case ltt == "":
+			add(name, "MissingLastTransitionTime", "lastTransitionTime is missing")
+		default:
+			if _, err := time.Parse(time.RFC3339, ltt); err != nil {
+				add(name, "InvalidLastTransitionTime", "lastTransitionTime %q is not RFC3339", ltt)
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,79 @@
+package checkconditions
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestLintConditions(t *testing.T) {
+	valid := map[string]interface{}{
+		"type": "Ready", "status": "True", "reason": "AllGood",
+		"lastTransitionTime": "2024-05-01T10:00:00Z",
+	}
+	obj := newTestObject(podsGVR, "Pod", "default", "linted",
+		valid,
+		map[string]interface{}{
+			"type": "Ready", "status": "true", "reason": "not camel",
+			"lastTransitionTime": "2024-05-01 10:00:00",
+		},
+		map[string]interface{}{"status": "True"},
+	)
+	findings := lintConditions(podsGVR, *obj, []interface{}{valid})
+	if len(findings) != 0 {
+		t.Fatalf("expected no findings for a valid condition, got %v", findings)
+	}
+
+	conditions, _, _ := conditionsOf(podsGVR, *obj)
+	var reasons []string
+	for _, f := range lintConditions(podsGVR, *obj, conditions) {
+		if f.Name != "linted" || f.Namespace != "default" || f.Type != lintConditionType {
+			t.Errorf("finding does not identify the object: %+v", f)
+		}
+		reasons = append(reasons, f.Reason)
+	}
+	want := []string{
+		"DuplicateType", "InvalidStatus", "ReasonNotCamelCase", "InvalidLastTransitionTime",
+		"MissingType", "MissingReason", "MissingLastTransitionTime",
+	}
+	if !slices.Equal(reasons, want) {
+		t.Errorf("unexpected reasons %v", reasons)
+	}
+}
+
+func TestInvalidConditionsAreFindings(t *testing.T) {
+	notAList := newTestObject(podsGVR, "Pod", "default", "not-a-list")
+	if err := unstructured.SetNestedField(notAList.Object, "broken", "status", "conditions"); err != nil {
+		t.Fatal(err)
+	}
+	notAMap := newTestObject(podsGVR, "Pod", "default", "not-a-map")
+	if err := unstructured.SetNestedSlice(notAMap.Object, []interface{}{"Ready"}, "status", "conditions"); err != nil {
+		t.Fatal(err)
+	}
+	lints := newTestObject(podsGVR, "Pod", "default", "lints", map[string]interface{}{"type": "Ready", "status": "True"})
+	lists := []resourceList{{gvr: podsGVR, list: &unstructured.UnstructuredList{
+		Items: []unstructured.Unstructured{*notAList, *notAMap, *lints},
+	}}}
+
+	counter, err := checkResourceLists(&Arguments{}, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"  default pods not-a-list Invalid conditions: .status.conditions accessor error: broken is of the type string, expected []interface{} [warning]",
+		"  default pods not-a-map Invalid condition #1: expected an object, got string [warning]",
+	}
+	if !slices.Equal(counter.Lines, want) {
+		t.Fatalf("unexpected lines:\n%s", strings.Join(counter.Lines, "\n"))
+	}
+
+	counter, err = checkResourceLists(&Arguments{LintConditions: true}, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := linesContaining(counter.Lines, " lints Lint condition Ready: "); len(got) != 2 {
+		t.Errorf("expected 2 lint findings, got %v", counter.Lines)
+	}
+}
@@ -0,0 +1,104 @@
+package checkconditions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// listErrorType is the type of findings about resource types which could
+// not be listed. 403 Forbidden is not a finding, see
+// Counter.ForbiddenResources.
+const listErrorType = "ListError"
+
+// Reasons of list error findings.
+const (
+	listErrorConversionWebhook = "ConversionWebhook"
+	listErrorNotFound          = "NotFound"
+	listErrorServerError       = "ServerError"
+	listErrorTimeout           = "Timeout"
+	listErrorTooManyRequests   = "TooManyRequests"
+	listErrorNetwork           = "NetworkError"
+	listErrorOther             = "Other"
+	// listErrorDiscoveryFailed is the reason of an API group whose resource
+	// types are unknown, see newDiscoveryErrorFindings.
+	listErrorDiscoveryFailed = "DiscoveryFailed"
+)
+
+// classifyListError returns the reason of a list error finding. A failing
+// conversion webhook is a server error, too, but it has its own reason
+// because the fix is different: the webhook of the CRD is broken.
+func classifyListError(err error) string {
+	var netError net.Error
+	var status apierrors.APIStatus
+	switch {
+	case strings.Contains(err.Error(), "conversion webhook"):
+		return listErrorConversionWebhook
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netError) && netError.Timeout():
+		return listErrorTimeout
+	case apierrors.IsNotFound(err):
+		return listErrorNotFound
+	case apierrors.IsTooManyRequests(err):
+		return listErrorTooManyRequests
+	case errors.As(err, &netError):
+		return listErrorNetwork
+	case errors.As(err, &status) && status.Status().Code >= 500:
+		return listErrorServerError
+	}
+	return listErrorOther
+}
+
+// newListErrorFinding creates the finding for a resource type which could not
+// be listed. It has no namespace and name.
+func newListErrorFinding(gvr schema.GroupVersionResource, err error) Finding {
+	reason := classifyListError(err)
+	return Finding{
+		GVR:    gvr,
+		Type:   listErrorType,
+		Reason: reason,
+		Text:   fmt.Sprintf("%s %s: %v", gvr.GroupResource(), reason, err),
+	}
+}
+
+// newDiscoveryErrorFindings creates a list error finding for each group
+// version whose discovery failed, usually because of a broken aggregated API
+// (APIService). Its resource types are unknown, so their objects can't be
+// checked.
+func newDiscoveryErrorFindings(err *discovery.ErrGroupDiscoveryFailed) []Finding {
+	findings := make([]Finding, 0, len(err.Groups))
+	for gv, groupErr := range err.Groups {
+		findings = append(findings, Finding{
+			GVR:    gv.WithResource(""),
+			Type:   listErrorType,
+			Reason: listErrorDiscoveryFailed,
+			Text: fmt.Sprintf("%s %s: %v, see kubectl get apiservice %s.%s",
+				gv, listErrorDiscoveryFailed, groupErr, gv.Version, gv.Group),
+		})
+	}
+	slices.SortFunc(findings, func(a, b Finding) int {
+		return strings.Compare(a.Text, b.Text)
+	})
+	return findings
+}
+
+// writeListErrors writes the section with the resource types which could
+// not be listed. Their objects were not checked, so the result of the scan
+// is unhealthy.
+func writeListErrors(w io.Writer, listErrors []Finding) {
+	if len(listErrors) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Errors listing %d resource types, their objects were not checked:\n", len(listErrors))
+	for _, f := range listErrors {
+		fmt.Fprintf(w, "  %s\n", f.TextWithSeverity())
+	}
+}
@@ -0,0 +1,63 @@
+package checkconditions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestClassifyListError(t *testing.T) {
+	for want, err := range map[string]error{
+		listErrorConversionWebhook: apierrors.NewInternalError(errors.New(`conversion webhook for example.com/v1, Kind=Widget failed: Post "https://webhook.example.svc:443/convert": dial tcp: connection refused`)),
+		listErrorNotFound:          apierrors.NewNotFound(widgetsGVR.GroupResource(), ""),
+		listErrorServerError:       apierrors.NewServiceUnavailable("etcd is down"),
+		listErrorTimeout:           apierrors.NewTimeoutError("list took too long", 0),
+		listErrorOther:             errors.New("boom"),
+	} {
+		if got := classifyListError(fmt.Errorf("listing: %w", err)); got != want {
+			t.Errorf("%v: got %s, want %s", err, got, want)
+		}
+	}
+	if got := classifyListError(context.DeadlineExceeded); got != listErrorTimeout {
+		t.Errorf("expected a deadline to be a timeout, got %s", got)
+	}
+}
+
+func TestListErrorsAreFindings(t *testing.T) {
+	c := newTestCluster([]string{"team-a"},
+		newTestObject(podsGVR, "Pod", "team-a", "pod-a", readyCondition("False")),
+		newTestObject(widgetsGVR, "Widget", "team-a", "widget-a", readyCondition("False")),
+	)
+	c.dynamic.PrependReactor("list", "widgets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewInternalError(errors.New("conversion webhook for example.com/v1, Kind=Widget failed: connection refused"))
+	})
+	args := &Arguments{FailOn: SeverityCritical}
+	counter, err := RunAndGetCounterWithClients(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.ListErrors) != 1 {
+		t.Fatalf("expected one list error, got %v", counter.ListErrors)
+	}
+	f := counter.ListErrors[0]
+	if f.Reason != listErrorConversionWebhook || f.Severity != SeverityError {
+		t.Errorf("unexpected list error %+v", f)
+	}
+	if len(counter.ForbiddenResources) != 0 || len(linesContaining(counter.Lines, "widget")) != 0 {
+		t.Errorf("list errors are not lines: %v", counter.Lines)
+	}
+	// --fail-on critical ignores the warning of the pod, but not that
+	// widgets could not be checked.
+	unhealthy, err := printCounter(args, &counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !unhealthy {
+		t.Error("expected list errors to make the scan unhealthy")
+	}
+}
@@ -0,0 +1,235 @@
+package checkconditions
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// GroupByOwner groups the findings by the top-most owner of the objects.
+const GroupByOwner = "owner"
+
+// GraphFormats are the supported values of Arguments.Graph.
+var GraphFormats = []string{"dot", "mermaid"}
+
+// needsOwners reports whether the owner references of all listed objects
+// need to be collected.
+func (a *Arguments) needsOwners() bool {
+	return a.GroupBy == GroupByOwner || a.Graph != "" || a.collectOwners
+}
+
+// objectRef is the part of a listed object which is needed to walk the
+// ownerReferences.
+type objectRef struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+	uid       types.UID
+	// owners are the UIDs of the owners, the controller first.
+	owners []types.UID
+}
+
+func newObjectRef(gvr schema.GroupVersionResource, obj unstructured.Unstructured) objectRef {
+	ref := objectRef{
+		gvr:       gvr,
+		namespace: obj.GetNamespace(),
+		name:      obj.GetName(),
+		uid:       obj.GetUID(),
+	}
+	for _, o := range obj.GetOwnerReferences() {
+		if o.Controller != nil && *o.Controller {
+			ref.owners = append([]types.UID{o.UID}, ref.owners...)
+			continue
+		}
+		ref.owners = append(ref.owners, o.UID)
+	}
+	return ref
+}
+
+// ownerNode is an object in the owner tree of the findings.
+type ownerNode struct {
+	label    string
+	findings []Finding
+	children []*ownerNode
+	parent   *ownerNode
+}
+
+// ownerForest links each finding to its object, and each object to its
+// owner, as long as the owner was listed, too. Only objects with findings
+// and their owners are part of the forest.
+type ownerForest struct {
+	roots []*ownerNode
+	// nodes in the order they were created, used for stable graph ids.
+	nodes []*ownerNode
+}
+
+func objectLabel(namespace string, gvr schema.GroupVersionResource, name string) string {
+	return strings.TrimSpace(fmt.Sprintf("%s %s %s", namespace, gvr.Resource, name))
+}
+
+func buildOwnerForest(counter *Counter) *ownerForest {
+	byUID := make(map[types.UID]objectRef, len(counter.objects))
+	for _, o := range counter.objects {
+		if o.uid != "" {
+			byUID[o.uid] = o
+		}
+	}
+	forest := &ownerForest{}
+	nodes := map[string]*ownerNode{}
+	getNode := func(key, label string) (*ownerNode, bool) {
+		if n, ok := nodes[key]; ok {
+			return n, false
+		}
+		n := &ownerNode{label: label}
+		nodes[key] = n
+		forest.nodes = append(forest.nodes, n)
+		return n, true
+	}
+	parentOf := func(ref objectRef) (objectRef, bool) {
+		for _, uid := range ref.owners {
+			if owner, ok := byUID[uid]; ok {
+				return owner, true
+			}
+		}
+		return objectRef{}, false
+	}
+
+	for _, f := range counter.Findings {
+		label := objectLabel(f.Namespace, f.GVR, f.Name)
+		key := string(f.UID)
+		if key == "" {
+			// Objects from manifests may have no UID.
+			key = f.GVR.String() + "/" + label
+		}
+		node, _ := getNode(key, label)
+		node.findings = append(node.findings, f)
+
+		ref, ok := byUID[f.UID]
+		if !ok {
+			continue
+		}
+		// Walk up until an already linked node or the top-most owner.
+		seen := map[types.UID]bool{ref.uid: true}
+		for node.parent == nil {
+			owner, ok := parentOf(ref)
+			if !ok || seen[owner.uid] {
+				break
+			}
+			seen[owner.uid] = true
+			parent, created := getNode(string(owner.uid), objectLabel(owner.namespace, owner.gvr, owner.name))
+			node.parent = parent
+			parent.children = append(parent.children, node)
+			if !created {
+				break
+			}
+			node, ref = parent, owner
+		}
+	}
+	for _, n := range forest.nodes {
+		if n.parent == nil {
+			forest.roots = append(forest.roots, n)
+		}
+	}
+	sortNodes(forest.roots)
+	return forest
+}
+
+func sortNodes(nodes []*ownerNode) {
+	slices.SortFunc(nodes, func(a, b *ownerNode) int {
+		return strings.Compare(a.label, b.label)
+	})
+	for _, n := range nodes {
+		sortNodes(n.children)
+	}
+}
+
+// writeOwnerTree prints the findings as an indented tree below the
+// top-most owner:
+//
+//	default clusters my-cluster
+//	    Condition Ready=False ...
+//	  default machinedeployments my-cluster-md-0
+//	      Condition Ready=False ...
+func writeOwnerTree(w io.Writer, counter *Counter) {
+	var write func(n *ownerNode, depth int)
+	write = func(n *ownerNode, depth int) {
+		indent := strings.Repeat("  ", depth)
+		fmt.Fprintf(w, "%s%s\n", indent, n.label)
+		for _, f := range n.findings {
+			fmt.Fprintf(w, "%s    %s\n", indent, f.TextWithSeverity())
+		}
+		for _, c := range n.children {
+			write(c, depth+1)
+		}
+	}
+	for _, root := range buildOwnerForest(counter).roots {
+		write(root, 0)
+	}
+}
+
+// writeGraph writes the objects with findings and their owners as graph.
+// Objects with findings are highlighted.
+func writeGraph(w io.Writer, format string, counter *Counter) {
+	forest := buildOwnerForest(counter)
+	ids := make(map[*ownerNode]string, len(forest.nodes))
+	for i, n := range forest.nodes {
+		ids[n] = fmt.Sprintf("n%d", i)
+	}
+	labelLines := func(n *ownerNode) []string {
+		lines := []string{n.label}
+		for _, f := range n.findings {
+			lines = append(lines, f.TextWithSeverity())
+		}
+		return lines
+	}
+
+	switch format {
+	case "dot":
+		escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+		fmt.Fprintln(w, "digraph findings {")
+		fmt.Fprintln(w, "  rankdir=LR;")
+		fmt.Fprintln(w, "  node [shape=box];")
+		for _, n := range forest.nodes {
+			lines := labelLines(n)
+			for i := range lines {
+				lines[i] = escape.Replace(lines[i])
+			}
+			attrs := ""
+			if len(n.findings) > 0 {
+				attrs = ", color=red"
+			}
+			fmt.Fprintf(w, "  %s [label=\"%s\"%s];\n", ids[n], strings.Join(lines, `\l`)+`\l`, attrs)
+		}
+		for _, n := range forest.nodes {
+			if n.parent != nil {
+				fmt.Fprintf(w, "  %s -> %s;\n", ids[n.parent], ids[n])
+			}
+		}
+		fmt.Fprintln(w, "}")
+	case "mermaid":
+		escape := strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+		fmt.Fprintln(w, "flowchart LR")
+		for _, n := range forest.nodes {
+			lines := labelLines(n)
+			for i := range lines {
+				lines[i] = escape.Replace(lines[i])
+			}
+			class := ""
+			if len(n.findings) > 0 {
+				class = ":::unhealthy"
+			}
+			fmt.Fprintf(w, "  %s[\"%s\"]%s\n", ids[n], strings.Join(lines, "<br/>"), class)
+		}
+		for _, n := range forest.nodes {
+			if n.parent != nil {
+				fmt.Fprintf(w, "  %s --> %s\n", ids[n.parent], ids[n])
+			}
+		}
+		fmt.Fprintln(w, "  classDef unhealthy fill:#fdd,stroke:#c00")
+	}
+}
@@ -0,0 +1,112 @@
+package checkconditions
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func ownedObject(apiVersion, kind, name, uid string, owner *unstructured.Unstructured, conditions ...map[string]interface{}) unstructured.Unstructured {
+	obj := unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetUID(types.UID(uid))
+	if owner != nil {
+		controller := true
+		obj.SetOwnerReferences([]metav1.OwnerReference{{
+			APIVersion: owner.GetAPIVersion(),
+			Kind:       owner.GetKind(),
+			Name:       owner.GetName(),
+			UID:        owner.GetUID(),
+			Controller: &controller,
+		}})
+	}
+	if len(conditions) > 0 {
+		list := make([]interface{}, 0, len(conditions))
+		for _, c := range conditions {
+			list = append(list, c)
+		}
+		_ = unstructured.SetNestedSlice(obj.Object, list, "status", "conditions")
+	}
+	return obj
+}
+
+func ownerTestCounter(t *testing.T) Counter {
+	t.Helper()
+	cluster := ownedObject("cluster.x-k8s.io/v1beta1", "Cluster", "c1", "uid-cluster", nil, readyCondition("False"))
+	md := ownedObject("cluster.x-k8s.io/v1beta1", "MachineDeployment", "c1-md", "uid-md", &cluster)
+	ms := ownedObject("cluster.x-k8s.io/v1beta1", "MachineSet", "c1-md-x", "uid-ms", &md)
+	m1 := ownedObject("cluster.x-k8s.io/v1beta1", "Machine", "c1-md-x-1", "uid-m1", &ms, readyCondition("False"))
+	m2 := ownedObject("cluster.x-k8s.io/v1beta1", "Machine", "c1-md-x-2", "uid-m2", &ms, readyCondition("True"))
+	lonely := ownedObject("v1", "Pod", "lonely", "uid-pod", nil, readyCondition("False"))
+
+	args := &Arguments{GroupBy: GroupByOwner}
+	counter, err := checkResourceLists(args, groupByResource([]unstructured.Unstructured{m1, m2, ms, md, cluster, lonely}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return counter
+}
+
+func TestWriteOwnerTree(t *testing.T) {
+	counter := ownerTestCounter(t)
+	var buf bytes.Buffer
+	writeOwnerTree(&buf, &counter)
+	want := `default clusters c1
+    Condition Ready=False Testing "set by test" () [warning]
+  default machinedeployments c1-md
+    default machinesets c1-md-x
+      default machines c1-md-x-1
+          Condition Ready=False Testing "set by test" () [warning]
+default pods lonely
+    Condition Ready=False Testing "set by test" () [warning]
+`
+	if buf.String() != want {
+		t.Fatalf("unexpected tree:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteGraph(t *testing.T) {
+	counter := ownerTestCounter(t)
+
+	var dot bytes.Buffer
+	writeGraph(&dot, "dot", &counter)
+	out := dot.String()
+	if !strings.HasPrefix(out, "digraph findings {") {
+		t.Fatalf("not a dot graph:\n%s", out)
+	}
+	if strings.Count(out, "->") != 3 {
+		t.Errorf("expected 3 edges (cluster -> md -> ms -> machine), got:\n%s", out)
+	}
+	if strings.Contains(out, "c1-md-x-2") {
+		t.Errorf("healthy machine without findings should not be part of the graph:\n%s", out)
+	}
+	if !strings.Contains(out, `\"set by test\"`) {
+		t.Errorf("quotes should be escaped:\n%s", out)
+	}
+
+	var mermaid bytes.Buffer
+	writeGraph(&mermaid, "mermaid", &counter)
+	out = mermaid.String()
+	if !strings.HasPrefix(out, "flowchart LR") {
+		t.Fatalf("not a mermaid graph:\n%s", out)
+	}
+	if strings.Count(out, ":::unhealthy") != 3 {
+		t.Errorf("expected 3 unhealthy nodes:\n%s", out)
+	}
+}
+
+func TestValidateGroupByAndGraph(t *testing.T) {
+	if err := (&Arguments{GroupBy: "namespace"}).validate(); err == nil {
+		t.Error("expected error for unknown --group-by")
+	}
+	if err := (&Arguments{Graph: "png"}).validate(); err == nil {
+		t.Error("expected error for unknown --graph")
+	}
+}
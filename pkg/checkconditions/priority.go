@@ -0,0 +1,130 @@
+package checkconditions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+const priorityCacheFile = "priority.json"
+
+// priorityMaxAge is how long a resource type stays prioritized after its
+// last finding.
+var priorityMaxAge = 7 * 24 * time.Hour
+
+// priorityCache remembers which resource types had findings, so that they
+// can be checked first in the next run. Stored per cluster, see cacheDir.
+type priorityCache struct {
+	path string
+	// Types is keyed by group resource, for example "deployments.apps".
+	Types map[string]*priorityEntry `json:"types"`
+}
+
+type priorityEntry struct {
+	// Findings is the number of lines of the last run.
+	Findings    int       `json:"findings"`
+	LastFinding time.Time `json:"lastFinding,omitempty"`
+	// ListDuration is the latency of the last LIST request.
+	ListDuration time.Duration `json:"listDuration"`
+}
+
+// loadPriorityCache reads the cache of the cluster. A missing or broken
+// file results in an empty cache, since the cache only affects the order.
+func loadPriorityCache(cacheKey string) *priorityCache {
+	cache := &priorityCache{Types: map[string]*priorityEntry{}}
+	dir, err := cacheDir(cacheKey)
+	if err != nil {
+		return cache
+	}
+	cache.path = filepath.Join(dir, priorityCacheFile)
+	data, err := os.ReadFile(cache.path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Failed to read priority cache: %v\n", err)
+		}
+		return cache
+	}
+	if err := json.Unmarshal(data, cache); err != nil {
+		fmt.Fprintf(os.Stderr, "Ignoring broken priority cache %s: %v\n", cache.path, err)
+	}
+	if cache.Types == nil {
+		cache.Types = map[string]*priorityEntry{}
+	}
+	return cache
+}
+
+func (c *priorityCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+func (c *priorityCache) prioritized(key string, now time.Time) (*priorityEntry, bool) {
+	e, ok := c.Types[key]
+	if !ok || e.LastFinding.IsZero() {
+		return nil, false
+	}
+	return e, now.Sub(e.LastFinding) < priorityMaxAge
+}
+
+// order moves the types with recent findings to the front, the fastest
+// first, so that the first findings show up as soon as possible. The other
+// types keep the discovery order.
+func (c *priorityCache) order(inputs []handleResourceTypeInput, now time.Time) []handleResourceTypeInput {
+	var first, rest []handleResourceTypeInput
+	for _, input := range inputs {
+		if _, ok := c.prioritized(input.gvr.GroupResource().String(), now); ok {
+			input.prioritized = true
+			first = append(first, input)
+			continue
+		}
+		rest = append(rest, input)
+	}
+	slices.SortStableFunc(first, func(a, b handleResourceTypeInput) int {
+		da := c.Types[a.gvr.GroupResource().String()].ListDuration
+		db := c.Types[b.gvr.GroupResource().String()].ListDuration
+		switch {
+		case da < db:
+			return -1
+		case da > db:
+			return 1
+		}
+		return 0
+	})
+	return append(first, rest...)
+}
+
+// update stores the outcome of a run.
+func (c *priorityCache) update(stats []resourceTypeStat, now time.Time) {
+	for _, stat := range stats {
+		key := stat.gvr.GroupResource().String()
+		e, ok := c.Types[key]
+		if !ok {
+			e = &priorityEntry{}
+			c.Types[key] = e
+		}
+		e.Findings = stat.lines
+		e.ListDuration = stat.listDuration
+		if stat.lines > 0 {
+			e.LastFinding = now
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package checkconditions
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestPriorityCacheOrder(t *testing.T) {
+	now := time.Now()
+	deploymentsGVR := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	cache := &priorityCache{Types: map[string]*priorityEntry{
+		"widgets.example.com": {Findings: 1, LastFinding: now.Add(-time.Hour), ListDuration: 300 * time.Millisecond},
+		"nodes":               {Findings: 2, LastFinding: now.Add(-time.Minute), ListDuration: 10 * time.Millisecond},
+		"deployments.apps":    {Findings: 1, LastFinding: now.Add(-priorityMaxAge - time.Hour)},
+		"pods":                {ListDuration: time.Millisecond},
+	}}
+	inputs := []handleResourceTypeInput{
+		{gvr: podsGVR},
+		{gvr: deploymentsGVR},
+		{gvr: widgetsGVR},
+		{gvr: nodesGVR},
+	}
+	ordered := cache.order(inputs, now)
+	want := []schema.GroupVersionResource{nodesGVR, widgetsGVR, podsGVR, deploymentsGVR}
+	for i, input := range ordered {
+		if input.gvr != want[i] {
+			t.Fatalf("position %d: expected %s, got %s", i, want[i], input.gvr)
+		}
+		if input.prioritized != (i < 2) {
+			t.Errorf("%s: unexpected prioritized=%v", input.gvr, input.prioritized)
+		}
+	}
+}
+
+func TestPriorityCacheUpdateAndSave(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	now := time.Now()
+	cache := loadPriorityCache("my-cluster")
+	cache.update([]resourceTypeStat{
+		{gvr: podsGVR, lines: 2, listDuration: 5 * time.Millisecond},
+		{gvr: nodesGVR, lines: 0, listDuration: 7 * time.Millisecond},
+	}, now)
+	if err := cache.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := loadPriorityCache("my-cluster")
+	if _, ok := loaded.prioritized("pods", now); !ok {
+		t.Error("pods had findings and should be prioritized")
+	}
+	if _, ok := loaded.prioritized("nodes", now); ok {
+		t.Error("nodes had no findings and should not be prioritized")
+	}
+	if loaded.Types["nodes"].ListDuration != 7*time.Millisecond {
+		t.Errorf("list duration was not stored: %v", loaded.Types["nodes"].ListDuration)
+	}
+}
+
+func TestRunWithPriorityPrintsEarlyLinesOnce(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	c := defaultTestCluster()
+	c.clients.CacheKey = "test-cluster"
+	args := &Arguments{Priority: true}
+
+	counter, err := RunAndGetCounterWithClients(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.earlyLines) != 0 {
+		t.Fatalf("first run has no cache, nothing should be printed early: %v", counter.earlyLines)
+	}
+
+	counter, err = RunAndGetCounterWithClients(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	early := 0
+	for _, n := range counter.earlyLines {
+		early += n
+	}
+	if early != len(counter.Lines) {
+		t.Fatalf("all types with findings should be prioritized in the second run: %d early, %d lines", early, len(counter.Lines))
+	}
+}
+
+func TestRunWithPriorityAndGraph(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	c := defaultTestCluster()
+	c.clients.CacheKey = "test-cluster"
+	for _, format := range GraphFormats {
+		args := &Arguments{Priority: true, Graph: format}
+		for run := 0; run < 2; run++ {
+			// The second run has a priority cache.
+			out := captureStdout(t, func() {
+				if _, err := RunCheckAllConditionsWithClients(context.Background(), c.clients, args); err != nil {
+					t.Fatal(err)
+				}
+			})
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			switch format {
+			case "dot":
+				if lines[0] != "digraph findings {" || lines[len(lines)-1] != "}" {
+					t.Fatalf("run %d: not a dot graph:\n%s", run, out)
+				}
+			case "mermaid":
+				if lines[0] != "flowchart LR" {
+					t.Fatalf("run %d: not a mermaid graph:\n%s", run, out)
+				}
+			}
+		}
+	}
+}
+
+// captureStdout returns what f writes to os.Stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	defer func() {
+		os.Stdout = stdout
+	}()
+	f()
+	w.Close()
+	return <-done
+}
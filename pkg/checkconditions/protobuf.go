@@ -0,0 +1,202 @@
+package checkconditions
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	restclient "k8s.io/client-go/rest"
+)
+
+// resourceLister lists the objects of one resource type.
+// dynamic.ResourceInterface is one, protobufResource is another.
+type resourceLister interface {
+	List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error)
+}
+
+// protobufLister lists the built-in types (the types of client-go's scheme)
+// with protobuf instead of JSON, see Arguments.Protobuf. The typed objects
+// are converted to unstructured ones, so that the checks don't see a
+// difference. Without checkers and kept lists, only the fields which the
+// builtin checks read are converted, see reducedObject.
+type protobufLister struct {
+	config *restclient.Config
+	mu     sync.Mutex
+	// clients per group version, created on first use.
+	clients map[schema.GroupVersion]restclient.Interface
+}
+
+func newProtobufLister(config *restclient.Config) *protobufLister {
+	return &protobufLister{config: restclient.CopyConfig(config), clients: map[schema.GroupVersion]restclient.Interface{}}
+}
+
+// supports reports whether the list kind of a resource is a built-in type.
+// kind is the kind of the items, from discovery.
+func (p *protobufLister) supports(gvr schema.GroupVersionResource, kind string) bool {
+	return kind != "" && scheme.Scheme.Recognizes(gvr.GroupVersion().WithKind(kind+"List"))
+}
+
+func (p *protobufLister) client(gv schema.GroupVersion) (restclient.Interface, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.clients[gv]; ok {
+		return c, nil
+	}
+	config := restclient.CopyConfig(p.config)
+	config.GroupVersion = &gv
+	config.APIPath = "/apis"
+	if gv.Group == "" {
+		config.APIPath = "/api"
+	}
+	config.ContentType = runtime.ContentTypeProtobuf
+	config.AcceptContentTypes = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
+	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	c, err := restclient.RESTClientFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating protobuf client for %s: %w", gv, err)
+	}
+	p.clients[gv] = c
+	return c, nil
+}
+
+// resource returns the lister of a resource type. Empty namespace means all
+// namespaces, or a cluster-scoped type. reduce converts only the fields which
+// the builtin checks read, see Arguments.reduceProtobuf.
+func (p *protobufLister) resource(gvr schema.GroupVersionResource, kind, namespace string, reduce bool) resourceLister {
+	return &protobufResource{lister: p, gvr: gvr, kind: kind, namespace: namespace, reduce: reduce}
+}
+
+type protobufResource struct {
+	lister    *protobufLister
+	gvr       schema.GroupVersionResource
+	kind      string
+	namespace string
+	reduce    bool
+}
+
+func (r *protobufResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	c, err := r.lister.client(r.gvr.GroupVersion())
+	if err != nil {
+		return nil, err
+	}
+	obj, err := c.Get().
+		NamespaceIfScoped(r.namespace, r.namespace != "").
+		Resource(r.gvr.Resource).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Do(ctx).
+		Get()
+	if err != nil {
+		return nil, err
+	}
+	return toUnstructuredList(obj, r.gvr.GroupVersion().WithKind(r.kind), r.reduce)
+}
+
+// reduceProtobuf reports whether objects listed with protobuf may be reduced
+// to the fields which the builtin checks read. Checkers and the kept lists
+// (snapshot, inventory, learn) get the full objects.
+func (a *Arguments) reduceProtobuf() bool {
+	return !a.keepLists && len(a.checkers().Names()) == 0
+}
+
+// toUnstructuredList converts a typed list, with reduce only the fields of
+// reducedObject. Decoded items have no apiVersion and kind, so they are set
+// from gvk.
+func toUnstructuredList(obj runtime.Object, gvk schema.GroupVersionKind, reduce bool) (*unstructured.UnstructuredList, error) {
+	items, err := meta.ExtractList(obj)
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{Items: make([]unstructured.Unstructured, 0, len(items))}
+	for _, item := range items {
+		var m map[string]interface{}
+		if reduce {
+			m, err = reducedObject(item)
+		} else {
+			m, err = runtime.DefaultUnstructuredConverter.ToUnstructured(item)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error converting %s: %w", gvk.Kind, err)
+		}
+		u := unstructured.Unstructured{Object: m}
+		u.SetGroupVersionKind(gvk)
+		list.Items = append(list.Items, u)
+	}
+	return list, nil
+}
+
+// reducedObject converts only the fields of a typed object which the checks
+// read: the metadata without labels, status.conditions and
+// status.observedGeneration. Converting the spec and the rest of the status
+// would need more memory than decoding JSON.
+func reducedObject(item runtime.Object) (map[string]interface{}, error) {
+	accessor, err := meta.Accessor(item)
+	if err != nil {
+		return nil, err
+	}
+	objectMeta := metav1.ObjectMeta{
+		Name:              accessor.GetName(),
+		Namespace:         accessor.GetNamespace(),
+		UID:               accessor.GetUID(),
+		Generation:        accessor.GetGeneration(),
+		CreationTimestamp: accessor.GetCreationTimestamp(),
+		DeletionTimestamp: accessor.GetDeletionTimestamp(),
+		Annotations:       accessor.GetAnnotations(),
+		OwnerReferences:   accessor.GetOwnerReferences(),
+		ManagedFields:     specManagedFields(accessor.GetManagedFields()),
+	}
+	metadata, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&objectMeta)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{"metadata": metadata}
+
+	v := reflect.Indirect(reflect.ValueOf(item))
+	if v.Kind() != reflect.Struct {
+		return m, nil
+	}
+	status := v.FieldByName("Status")
+	if status.Kind() != reflect.Struct {
+		return m, nil
+	}
+	reduced := struct {
+		Conditions         interface{} `json:"conditions,omitempty"`
+		ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	}{}
+	if c := status.FieldByName("Conditions"); c.Kind() == reflect.Slice && c.Len() > 0 {
+		reduced.Conditions = c.Interface()
+	}
+	if g := status.FieldByName("ObservedGeneration"); g.Kind() == reflect.Int64 {
+		reduced.ObservedGeneration = g.Int()
+	}
+	if reduced.Conditions == nil && reduced.ObservedGeneration == 0 {
+		return m, nil
+	}
+	if m["status"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(&reduced); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// specManagedFields returns the managedFields entries which changed the
+// spec, see specChangedAt. Their fields are reduced to the spec marker.
+func specManagedFields(managedFields []metav1.ManagedFieldsEntry) []metav1.ManagedFieldsEntry {
+	var result []metav1.ManagedFieldsEntry
+	for _, e := range managedFields {
+		if e.Subresource != "" || e.Time == nil || e.FieldsV1 == nil || !strings.Contains(string(e.FieldsV1.Raw), `"f:spec"`) {
+			continue
+		}
+		result = append(result, metav1.ManagedFieldsEntry{
+			Manager: e.Manager, Operation: e.Operation, Time: e.Time,
+			FieldsType: e.FieldsType, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{}}`)},
+		})
+	}
+	return result
+}
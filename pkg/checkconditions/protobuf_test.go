@@ -0,0 +1,240 @@
+package checkconditions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	restclient "k8s.io/client-go/rest"
+)
+
+// podListServer serves /api/v1/pods with n pods, as protobuf or JSON
+// depending on the Accept header.
+func podListServer(t testing.TB, n int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	list := &corev1.PodList{}
+	ltt := metav1.NewTime(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	for i := 0; i < n; i++ {
+		status := corev1.ConditionTrue
+		if i%10 == 0 {
+			status = corev1.ConditionFalse
+		}
+		list.Items = append(list.Items, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("pod-%d", i), UID: "uid", Labels: map[string]string{"app": "bench"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "registry.example.com/app:v1"}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning, Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: status, Reason: "ContainersNotReady", Message: "containers with unready status: [app]", LastTransitionTime: ltt},
+				{Type: corev1.PodScheduled, Status: corev1.ConditionTrue, LastTransitionTime: ltt},
+			}},
+		})
+	}
+	encoded := map[string][]byte{}
+	for _, mediaType := range []string{runtime.ContentTypeProtobuf, runtime.ContentTypeJSON} {
+		info, ok := runtime.SerializerInfoForMediaType(scheme.Codecs.SupportedMediaTypes(), mediaType)
+		if !ok {
+			t.Fatalf("no serializer for %s", mediaType)
+		}
+		data, err := runtime.Encode(scheme.Codecs.EncoderForVersion(info.Serializer, corev1.SchemeGroupVersion), list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded[mediaType] = data
+	}
+	var protobufRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/pods" {
+			http.NotFound(w, r)
+			return
+		}
+		mediaType := runtime.ContentTypeJSON
+		if strings.Contains(r.Header.Get("Accept"), runtime.ContentTypeProtobuf) {
+			mediaType = runtime.ContentTypeProtobuf
+			protobufRequests.Add(1)
+		}
+		w.Header().Set("Content-Type", mediaType)
+		_, _ = w.Write(encoded[mediaType])
+	}))
+	t.Cleanup(server.Close)
+	return server, &protobufRequests
+}
+
+func TestProtobufLister(t *testing.T) {
+	server, protobufRequests := podListServer(t, 20)
+	clients, err := NewClients(&restclient.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !clients.protobuf.supports(podsGVR, "Pod") || clients.protobuf.supports(widgetsGVR, "Widget") {
+		t.Fatal("expected only built-in types to be supported")
+	}
+	ctx := context.Background()
+	viaJSON, err := clients.Dynamic.Resource(podsGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	viaProtobuf, err := clients.protobuf.resource(podsGVR, "Pod", "", true).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if protobufRequests.Load() != 1 {
+		t.Errorf("expected one protobuf request, got %d", protobufRequests.Load())
+	}
+	if len(viaProtobuf.Items) != 20 || viaProtobuf.Items[0].GetKind() != "Pod" || viaProtobuf.Items[0].GetAPIVersion() != "v1" {
+		t.Fatalf("unexpected items %v", viaProtobuf.Items)
+	}
+
+	// The checks can't tell the difference.
+	args := &Arguments{Now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	jsonOutput := checkList(args, podsGVR, viaJSON, 0)
+	protobufOutput := checkList(args, podsGVR, viaProtobuf, 0)
+	if len(jsonOutput.findings) != 2 || jsonOutput.checkedConditions != protobufOutput.checkedConditions {
+		t.Fatalf("unexpected findings %v", jsonOutput.findings)
+	}
+	for i := range jsonOutput.findings {
+		if jsonOutput.findings[i].Line() != protobufOutput.findings[i].Line() {
+			t.Errorf("JSON %q != protobuf %q", jsonOutput.findings[i].Line(), protobufOutput.findings[i].Line())
+		}
+	}
+}
+
+func TestProtobufWithChecker(t *testing.T) {
+	server, protobufRequests := podListServer(t, 20)
+	clients, err := NewClients(&restclient.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkers := &CheckerRegistry{}
+	checkers.Register("image", ObjectCheckerFunc(func(gvr schema.GroupVersionResource, obj unstructured.Unstructured) []Finding {
+		containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "containers")
+		if len(containers) == 0 {
+			return []Finding{{Type: "NoSpec"}}
+		}
+		return nil
+	}))
+	args := &Arguments{Protobuf: true, Checkers: checkers}
+	if args.reduceProtobuf() {
+		t.Fatal("objects must not be reduced if checkers are registered")
+	}
+	output := handleResourceType(context.Background(), handleResourceTypeInput{
+		args: args, dynClient: clients.Dynamic, protobuf: clients.protobuf, gvr: podsGVR, kind: "Pod", namespaced: true,
+	})
+	if protobufRequests.Load() != 1 {
+		t.Fatalf("expected one protobuf request, got %d", protobufRequests.Load())
+	}
+	if output.checkedResources != 20 {
+		t.Fatalf("expected 20 checked pods, got %d", output.checkedResources)
+	}
+	for _, f := range output.findings {
+		if f.Type == "NoSpec" {
+			t.Fatalf("the checker should see the spec: %s", f.Line())
+		}
+	}
+
+	if !(&Arguments{Checkers: &CheckerRegistry{}}).reduceProtobuf() {
+		t.Error("objects should be reduced without checkers")
+	}
+	if (&Arguments{Checkers: &CheckerRegistry{}, keepLists: true}).reduceProtobuf() {
+		t.Error("kept lists, for example of a snapshot, must not be reduced")
+	}
+}
+
+func TestReducedObject(t *testing.T) {
+	created := metav1.NewTime(time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC))
+	specChanged := metav1.NewTime(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	statusChanged := metav1.NewTime(time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC))
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default", Name: "web", UID: "uid-web", Generation: 3, CreationTimestamp: created,
+			Labels:          map[string]string{"app": "web"},
+			Annotations:     map[string]string{AnnotationExpect: "Available=unhealthy"},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "example.com/v1", Kind: "Widget", Name: "widget-a", UID: "uid-widget-a"}},
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationApply, Time: &specChanged, FieldsType: "FieldsV1",
+					FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)}},
+				{Manager: "kube-controller-manager", Operation: metav1.ManagedFieldsOperationUpdate, Time: &statusChanged, FieldsType: "FieldsV1",
+					Subresource: "status", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{}}`)}},
+			},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, Conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse, Reason: "MinimumReplicasUnavailable", LastTransitionTime: statusChanged},
+		}},
+	}
+	full, err := runtime.DefaultUnstructuredConverter.ToUnstructured(deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reduced, err := reducedObject(deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fields := range [][]string{{"spec"}, {"status", "replicas"}, {"metadata", "labels"}} {
+		if _, found, _ := unstructured.NestedFieldNoCopy(reduced, fields...); found {
+			t.Errorf("%s should be dropped: %v", strings.Join(fields, "."), reduced)
+		}
+	}
+
+	// The checks can't tell the difference.
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	fullObj, reducedObj := unstructured.Unstructured{Object: full}, unstructured.Unstructured{Object: reduced}
+	if !specChangedAt(fullObj).Equal(specChangedAt(reducedObj)) {
+		t.Errorf("spec changed at %s, got %s", specChangedAt(fullObj), specChangedAt(reducedObj))
+	}
+	args := &Arguments{Now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), StaleAfter: time.Minute}
+	fullOutput := checkList(args, gvr, &unstructured.UnstructuredList{Items: []unstructured.Unstructured{fullObj}}, 0)
+	reducedOutput := checkList(args, gvr, &unstructured.UnstructuredList{Items: []unstructured.Unstructured{reducedObj}}, 0)
+	if len(fullOutput.findings) != 2 || len(reducedOutput.findings) != len(fullOutput.findings) {
+		t.Fatalf("expected Stale and Available findings, got %v and %v", fullOutput.findings, reducedOutput.findings)
+	}
+	for i := range fullOutput.findings {
+		if fullOutput.findings[i].Line() != reducedOutput.findings[i].Line() {
+			t.Errorf("full %q != reduced %q", fullOutput.findings[i].Line(), reducedOutput.findings[i].Line())
+		}
+	}
+	if !reflect.DeepEqual(newObjectRef(gvr, fullObj), newObjectRef(gvr, reducedObj)) {
+		t.Errorf("owner references differ: %+v != %+v", newObjectRef(gvr, fullObj), newObjectRef(gvr, reducedObj))
+	}
+	if got := reducedObj.GetAnnotations()[AnnotationExpect]; got != "Available=unhealthy" {
+		t.Errorf("annotations should be kept, got %v", reducedObj.GetAnnotations())
+	}
+}
+
+func benchmarkList(b *testing.B, protobuf bool) {
+	server, _ := podListServer(b, 2000)
+	clients, err := NewClients(&restclient.Config{Host: server.URL, QPS: 10000, Burst: 10000})
+	if err != nil {
+		b.Fatal(err)
+	}
+	var lister resourceLister = clients.Dynamic.Resource(podsGVR)
+	if protobuf {
+		lister = clients.protobuf.resource(podsGVR, "Pod", "", true)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		list, err := lister.List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(list.Items) != 2000 {
+			b.Fatalf("got %d items", len(list.Items))
+		}
+	}
+}
+
+// Compare with: go test -run xxx -bench List -benchmem ./pkg/checkconditions
+func BenchmarkListJSON(b *testing.B)     { benchmarkList(b, false) }
+func BenchmarkListProtobuf(b *testing.B) { benchmarkList(b, true) }
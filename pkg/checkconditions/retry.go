@@ -0,0 +1,94 @@
+package checkconditions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// listRetryDelay is the pause before the first retry of a LIST request. It
+// doubles with each retry, up to maxListRetryDelay.
+var listRetryDelay = 500 * time.Millisecond
+
+const maxListRetryDelay = 30 * time.Second
+
+// errAllListsFailed is returned by a scan if no resource type could be
+// listed because of transient errors. Usually the connection to the API
+// server is lost, so the whole scan gets retried.
+var errAllListsFailed = errors.New("all LIST requests failed")
+
+// isTransient reports whether an error is likely gone after a while:
+// network errors, timeouts, 429 Too Many Requests and 5xx. A failing
+// conversion webhook is a 500, too, but it needs a fix.
+func isTransient(err error) bool {
+	var netError net.Error
+	var status apierrors.APIStatus
+	switch {
+	case errors.Is(err, errAllListsFailed):
+		return true
+	case classifyListError(err) == listErrorConversionWebhook:
+		return false
+	case errors.As(err, &netError), errors.Is(err, context.DeadlineExceeded),
+		apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), apierrors.IsTooManyRequests(err):
+		return true
+	case errors.As(err, &status) && status.Status().Code >= 500:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the pause before the next attempt: exponential backoff,
+// but at least the Retry-After of the response.
+func retryAfter(err error, attempt int) time.Duration {
+	d := listRetryDelay << attempt
+	if d <= 0 || d > maxListRetryDelay {
+		d = maxListRetryDelay
+	}
+	if seconds, ok := apierrors.SuggestsClientDelay(err); ok {
+		d = max(d, time.Duration(seconds)*time.Second)
+	}
+	return d
+}
+
+// listWithRetries lists one resource type. Transient errors are retried
+// Arguments.ListRetries times, each request has Arguments.ListTimeout.
+func listWithRetries(ctx context.Context, args *Arguments, ri resourceLister) (*unstructured.UnstructuredList, error) {
+	for attempt := 0; ; attempt++ {
+		reqCtx, cancel := ctx, context.CancelFunc(func() {})
+		if args.ListTimeout > 0 {
+			reqCtx, cancel = context.WithTimeout(ctx, args.ListTimeout)
+		}
+		list, err := ri.List(reqCtx, metav1.ListOptions{})
+		cancel()
+		if err == nil || attempt >= args.ListRetries || !isTransient(err) {
+			if err != nil && attempt > 0 {
+				err = fmt.Errorf("%w (after %d attempts)", err, attempt+1)
+			}
+			return list, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retryAfter(err, attempt)):
+		}
+	}
+}
+
+// transientListErrors reports whether the list errors of a scan are all
+// transient.
+func transientListErrors(listErrors []Finding) bool {
+	for _, f := range listErrors {
+		switch f.Reason {
+		case listErrorTimeout, listErrorServerError, listErrorNetwork, listErrorTooManyRequests:
+		default:
+			return false
+		}
+	}
+	return true
+}
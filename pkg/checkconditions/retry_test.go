@@ -0,0 +1,105 @@
+package checkconditions
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func setListRetryDelay(t *testing.T, d time.Duration) {
+	t.Helper()
+	old := listRetryDelay
+	listRetryDelay = d
+	t.Cleanup(func() { listRetryDelay = old })
+}
+
+// failingLists makes the first n LIST requests of a resource ("*" for all)
+// fail with err.
+func failingLists(c *testCluster, resource string, n int, err error) *int {
+	var mu sync.Mutex
+	calls := 0
+	c.dynamic.PrependReactor("list", resource, func(action clienttesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls <= n {
+			return true, nil, err
+		}
+		return false, nil, nil
+	})
+	return &calls
+}
+
+func TestListWithRetries(t *testing.T) {
+	setListRetryDelay(t, time.Millisecond)
+	c := defaultTestCluster()
+	calls := failingLists(c, "widgets", 2, apierrors.NewServiceUnavailable("etcd is down"))
+	counter, err := RunAndGetCounterWithClients(context.Background(), c.clients, &Arguments{ListRetries: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 3 || len(counter.ListErrors) != 0 || len(linesContaining(counter.Lines, "widget-a")) != 1 {
+		t.Errorf("expected widgets to succeed with the third request, got %d calls: %v %v", *calls, counter.ListErrors, counter.Lines)
+	}
+
+	// Give up after the retries, the other types are checked anyway.
+	c = defaultTestCluster()
+	calls = failingLists(c, "widgets", 10, apierrors.NewServiceUnavailable("etcd is down"))
+	counter, err = RunAndGetCounterWithClients(context.Background(), c.clients, &Arguments{ListRetries: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 3 || len(counter.ListErrors) != 1 || counter.ListErrors[0].Reason != listErrorServerError {
+		t.Errorf("expected a server error after 3 calls, got %d calls: %v", *calls, counter.ListErrors)
+	}
+	if len(counter.Lines) != 3 {
+		t.Errorf("expected partial results, got %v", counter.Lines)
+	}
+
+	// A broken conversion webhook does not get better by retrying.
+	c = defaultTestCluster()
+	calls = failingLists(c, "widgets", 10, apierrors.NewInternalError(errors.New("conversion webhook for example.com/v1, Kind=Widget failed")))
+	if _, err := RunAndGetCounterWithClients(context.Background(), c.clients, &Arguments{ListRetries: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 1 {
+		t.Errorf("expected no retry, got %d calls", *calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	setListRetryDelay(t, 100*time.Millisecond)
+	if d := retryAfter(errors.New("boom"), 2); d != 400*time.Millisecond {
+		t.Errorf("expected exponential backoff, got %s", d)
+	}
+	if d := retryAfter(errors.New("boom"), 40); d != maxListRetryDelay {
+		t.Errorf("expected the maximum, got %s", d)
+	}
+	if d := retryAfter(apierrors.NewTooManyRequests("slow down", 2), 0); d != 2*time.Second {
+		t.Errorf("expected Retry-After to be honored, got %s", d)
+	}
+}
+
+func TestRunWithRetriesSurvivesLostConnection(t *testing.T) {
+	setRetryDelay(t, time.Millisecond)
+	c := defaultTestCluster()
+	// All three resource types fail during the first scan.
+	failingLists(c, "*", 3, newNetError())
+	args := &Arguments{RetryForEver: true, ProgrammStartTime: time.Now()}
+	counter, err := runWithRetries(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.ListErrors) != 0 || len(counter.Lines) != 4 {
+		t.Errorf("expected the second scan to succeed, got %v %v", counter.ListErrors, counter.Lines)
+	}
+	if !isTransient(errAllListsFailed) {
+		t.Error("expected a scan without any successful LIST to be transient")
+	}
+}
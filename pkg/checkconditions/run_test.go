@@ -0,0 +1,376 @@
+package checkconditions
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var (
+	podsGVR    = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	nodesGVR   = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	widgetsGVR = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+)
+
+// fakeDiscovery returns a fixed result from ServerPreferredResources, which
+// the client-go fake does not implement.
+type fakeDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	resources []*metav1.APIResourceList
+	// errs is consumed one entry per call. A nil entry or an exhausted
+	// slice means success.
+	errs  []error
+	calls int
+	// beforeCall runs at the start of each call with the 1-based call number.
+	beforeCall func(call int)
+}
+
+func (d *fakeDiscovery) ServerPreferredResources() ([]*metav1.APIResourceList, error) {
+	d.calls++
+	if d.beforeCall != nil {
+		d.beforeCall(d.calls)
+	}
+	if len(d.errs) > 0 {
+		err := d.errs[0]
+		d.errs = d.errs[1:]
+		if err != nil {
+			var groupErr *discovery.ErrGroupDiscoveryFailed
+			if errors.As(err, &groupErr) {
+				return d.resources, err
+			}
+			return nil, err
+		}
+	}
+	return d.resources, nil
+}
+
+func defaultAPIResources() []*metav1.APIResourceList {
+	return []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "pods", Namespaced: true, Kind: "Pod", Verbs: []string{"list"}},
+				{Name: "nodes", Namespaced: false, Kind: "Node", Verbs: []string{"list"}},
+				{Name: "secrets", Namespaced: true, Kind: "Secret", Verbs: []string{"list"}},
+			},
+		},
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", Namespaced: true, Kind: "Widget", Verbs: []string{"list"}},
+			},
+		},
+	}
+}
+
+func newTestObject(gvr schema.GroupVersionResource, kind, namespace, name string, conditions ...map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(gvr.GroupVersion().String())
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	if len(conditions) > 0 {
+		list := make([]interface{}, 0, len(conditions))
+		for _, c := range conditions {
+			list = append(list, c)
+		}
+		_ = unstructured.SetNestedSlice(obj.Object, list, "status", "conditions")
+	}
+	return obj
+}
+
+func readyCondition(status string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":    "Ready",
+		"status":  status,
+		"reason":  "Testing",
+		"message": "set by test",
+	}
+}
+
+type testCluster struct {
+	clients   *Clients
+	dynamic   *dynamicfake.FakeDynamicClient
+	discovery *fakeDiscovery
+}
+
+func newTestCluster(namespaces []string, objects ...runtime.Object) *testCluster {
+	nsObjects := make([]runtime.Object, 0, len(namespaces))
+	for _, ns := range namespaces {
+		nsObjects = append(nsObjects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}})
+	}
+	clientset := kubernetesfake.NewSimpleClientset(nsObjects...)
+	dyn := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			podsGVR:    "PodList",
+			nodesGVR:   "NodeList",
+			widgetsGVR: "WidgetList",
+		}, objects...)
+	disc := &fakeDiscovery{
+		FakeDiscovery: &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}},
+		resources:     defaultAPIResources(),
+	}
+	return &testCluster{
+		clients:   &Clients{Kubernetes: clientset, Dynamic: dyn, Discovery: disc},
+		dynamic:   dyn,
+		discovery: disc,
+	}
+}
+
+func defaultTestCluster() *testCluster {
+	return newTestCluster([]string{"team-a", "team-b", "kube-system"},
+		newTestObject(podsGVR, "Pod", "team-a", "pod-a", readyCondition("False")),
+		newTestObject(podsGVR, "Pod", "team-b", "pod-b", readyCondition("False")),
+		newTestObject(podsGVR, "Pod", "kube-system", "healthy", readyCondition("True")),
+		newTestObject(nodesGVR, "Node", "", "node-1", readyCondition("False")),
+		newTestObject(widgetsGVR, "Widget", "team-a", "widget-a", readyCondition("False")),
+	)
+}
+
+func linesContaining(lines []string, sub string) []string {
+	var found []string
+	for _, l := range lines {
+		if strings.Contains(l, sub) {
+			found = append(found, l)
+		}
+	}
+	return found
+}
+
+func TestRunAndGetCounterWithClientsAllNamespaces(t *testing.T) {
+	c := defaultTestCluster()
+	args := &Arguments{}
+	counter, err := RunAndGetCounterWithClients(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.Lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d: %v", len(counter.Lines), counter.Lines)
+	}
+	if counter.CheckedResources != 5 {
+		t.Errorf("expected 5 checked resources, got %d", counter.CheckedResources)
+	}
+	// pods, nodes and widgets. Secrets are skipped.
+	if counter.CheckedResourceTypes != 3 {
+		t.Errorf("expected 3 checked resource types, got %d", counter.CheckedResourceTypes)
+	}
+	if len(linesContaining(counter.Lines, "healthy")) != 0 {
+		t.Errorf("healthy pod should not be reported: %v", counter.Lines)
+	}
+}
+
+func TestRunAndGetCounterWithClientsNamespaceGlob(t *testing.T) {
+	c := defaultTestCluster()
+	args := &Arguments{NamespacePatterns: []string{"team-*"}}
+	counter, err := RunAndGetCounterWithClients(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(args.Namespaces, ","); got != "team-a,team-b" {
+		t.Fatalf("expected resolved namespaces team-a,team-b, got %q", got)
+	}
+	if len(linesContaining(counter.Lines, "node-1")) != 0 {
+		t.Errorf("cluster-scoped nodes should be skipped with -n: %v", counter.Lines)
+	}
+	if len(counter.Lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %v", len(counter.Lines), counter.Lines)
+	}
+}
+
+func TestRunAndGetCounterWithClientsNamespaceGlobNoMatch(t *testing.T) {
+	c := defaultTestCluster()
+	args := &Arguments{NamespacePatterns: []string{"nope-*"}}
+	_, err := RunAndGetCounterWithClients(context.Background(), c.clients, args)
+	if err == nil || !strings.Contains(err.Error(), "no namespace matches") {
+		t.Fatalf("expected no-match error, got %v", err)
+	}
+}
+
+func TestRunAndGetCounterWithClientsExclude(t *testing.T) {
+	c := defaultTestCluster()
+	args := &Arguments{
+		NamespacePatterns:        []string{"team-*"},
+		ExcludeNamespacePatterns: []string{"team-b"},
+	}
+	counter, err := RunAndGetCounterWithClients(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(linesContaining(counter.Lines, "team-b")) != 0 {
+		t.Errorf("excluded namespace was reported: %v", counter.Lines)
+	}
+	if len(counter.Lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %v", len(counter.Lines), counter.Lines)
+	}
+}
+
+func TestRunAndGetCounterWithClientsForbidden(t *testing.T) {
+	c := defaultTestCluster()
+	c.dynamic.PrependReactor("list", "widgets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(widgetsGVR.GroupResource(), "", errors.New("no access"))
+	})
+	counter, err := RunAndGetCounterWithClients(context.Background(), c.clients, &Arguments{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.ForbiddenResources) != 1 || counter.ForbiddenResources[0] != "widgets" {
+		t.Errorf("expected widgets to be forbidden, got %v", counter.ForbiddenResources)
+	}
+	if len(linesContaining(counter.Lines, "widget-a")) != 0 {
+		t.Errorf("forbidden resource should not produce lines: %v", counter.Lines)
+	}
+}
+
+func TestRunAndGetCounterWithClientsGroupDiscoveryFailed(t *testing.T) {
+	c := defaultTestCluster()
+	c.discovery.errs = []error{&discovery.ErrGroupDiscoveryFailed{
+		Groups: map[schema.GroupVersion]error{
+			{Group: "metrics.k8s.io", Version: "v1beta1"}: errors.New("service unavailable"),
+		},
+	}}
+	args := &Arguments{}
+	counter, err := RunAndGetCounterWithClients(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatalf("group discovery failures should not stop the scan, got %v", err)
+	}
+	if len(counter.Lines) != 4 {
+		t.Fatalf("expected partial discovery to still check resources, got %v", counter.Lines)
+	}
+	if len(counter.ListErrors) != 1 {
+		t.Fatalf("expected a list error for the broken group, got %v", counter.ListErrors)
+	}
+	f := counter.ListErrors[0]
+	if f.Reason != listErrorDiscoveryFailed || f.GVR.Group != "metrics.k8s.io" || f.Severity == 0 ||
+		!strings.Contains(f.Text, "apiservice v1beta1.metrics.k8s.io") {
+		t.Errorf("unexpected list error %+v", f)
+	}
+	if !counter.unhealthy(args) {
+		t.Error("a broken aggregated API should make the result unhealthy")
+	}
+}
+
+func TestRunAndGetCounterWithClientsDiscoveryError(t *testing.T) {
+	c := defaultTestCluster()
+	c.discovery.errs = []error{errors.New("boom")}
+	_, err := RunAndGetCounterWithClients(context.Background(), c.clients, &Arguments{})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected discovery error, got %v", err)
+	}
+}
+
+func setRetryDelay(t *testing.T, d time.Duration) {
+	t.Helper()
+	old := retryDelay
+	retryDelay = d
+	t.Cleanup(func() { retryDelay = old })
+}
+
+func newNetError() error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+}
+
+func TestRunCheckAllConditionsWithClientsRetriesNetworkErrors(t *testing.T) {
+	setRetryDelay(t, time.Millisecond)
+	c := defaultTestCluster()
+	c.discovery.errs = []error{newNetError(), newNetError()}
+	args := &Arguments{RetryCount: 5, ProgrammStartTime: time.Now()}
+	unhealthy, err := RunCheckAllConditionsWithClients(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !unhealthy {
+		t.Error("expected unhealthy result")
+	}
+	if c.discovery.calls != 3 {
+		t.Errorf("expected 3 discovery calls, got %d", c.discovery.calls)
+	}
+	if !args.RetryForEver {
+		t.Error("a successful connection should switch to retry forever")
+	}
+}
+
+func TestRunCheckAllConditionsWithClientsGivesUp(t *testing.T) {
+	setRetryDelay(t, time.Millisecond)
+	c := defaultTestCluster()
+	c.discovery.errs = []error{newNetError(), newNetError(), newNetError(), newNetError()}
+	args := &Arguments{RetryCount: 1, ProgrammStartTime: time.Now()}
+	_, err := RunCheckAllConditionsWithClients(context.Background(), c.clients, args)
+	if err == nil || !strings.Contains(err.Error(), "network error") {
+		t.Fatalf("expected network error, got %v", err)
+	}
+}
+
+func TestRunCheckAllConditionsWithClientsNoRetryOnOtherErrors(t *testing.T) {
+	setRetryDelay(t, time.Millisecond)
+	c := defaultTestCluster()
+	c.discovery.errs = []error{errors.New("boom")}
+	_, err := RunCheckAllConditionsWithClients(context.Background(), c.clients, &Arguments{RetryCount: 5})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if c.discovery.calls != 1 {
+		t.Errorf("expected no retry, got %d calls", c.discovery.calls)
+	}
+}
+
+func TestRunCheckAllConditionsWithClientsWhileRegex(t *testing.T) {
+	c := defaultTestCluster()
+	args := &Arguments{WhileRegex: regexp.MustCompile("widget-a")}
+	matched, err := RunCheckAllConditionsWithClients(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !matched {
+		t.Error("expected regex to match")
+	}
+
+	args = &Arguments{WhileRegex: regexp.MustCompile("does-not-exist")}
+	matched, err = RunCheckAllConditionsWithClients(context.Background(), c.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matched {
+		t.Error("expected regex not to match")
+	}
+}
+
+func TestRunWhileRegexStopsWhenHealthy(t *testing.T) {
+	c := defaultTestCluster()
+	// The widget becomes healthy before the third scan.
+	c.discovery.beforeCall = func(call int) {
+		if call != 3 {
+			return
+		}
+		healthy := newTestObject(widgetsGVR, "Widget", "team-a", "widget-a", readyCondition("True"))
+		if err := c.dynamic.Tracker().Update(widgetsGVR, healthy, "team-a"); err != nil {
+			t.Errorf("update failed: %v", err)
+		}
+	}
+	args := &Arguments{
+		WhileRegex:        regexp.MustCompile("widget-a"),
+		Clients:           c.clients,
+		ProgrammStartTime: time.Now(),
+	}
+	if err := RunWhileRegex(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+	if c.discovery.calls != 3 {
+		t.Errorf("expected 3 scans, got %d", c.discovery.calls)
+	}
+}
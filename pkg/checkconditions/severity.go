@@ -0,0 +1,272 @@
+package checkconditions
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Severity of a finding. The zero value means "not set".
+type Severity int
+
+const (
+	SeverityInfo Severity = iota + 1
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+// Severities are the names of all severities, lowest first.
+var Severities = []string{"info", "warning", "error", "critical"}
+
+func (s Severity) String() string {
+	if s < SeverityInfo || s > SeverityCritical {
+		return ""
+	}
+	return Severities[s-1]
+}
+
+// ParseSeverity parses one of Severities.
+func ParseSeverity(s string) (Severity, error) {
+	for i, name := range Severities {
+		if strings.EqualFold(s, name) {
+			return Severity(i + 1), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid severity %q, supported: %s", s, strings.Join(Severities, ", "))
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	parsed, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// defaultSeverity is used if no rule matches.
+const defaultSeverity = SeverityWarning
+
+// Rule assigns a severity to the findings it matches. Empty fields match
+// everything.
+type Rule struct {
+	// Name is shown in error messages only.
+	Name string `json:"name,omitempty"`
+	// Resource is a glob pattern for the resource name, for example "machines".
+	Resource string `json:"resource,omitempty"`
+	// Type is a glob pattern for the condition type. Findings which are not
+	// about a condition have a type, too, for example "DeletionTimestamp".
+	Type string `json:"type,omitempty"`
+	// Status is True, False or Unknown.
+	Status string `json:"status,omitempty"`
+	// Reason and Message are regular expressions.
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	// OlderThan only matches findings whose condition changed longer ago,
+	// for example "5m". Findings without lastTransitionTime don't match.
+	OlderThan *metav1.Duration `json:"olderThan,omitempty"`
+	Severity  Severity         `json:"severity"`
+
+	reason  *regexp.Regexp
+	message *regexp.Regexp
+}
+
+// RulesFile is the content of the file given via --rules.
+type RulesFile struct {
+	Rules      []Rule          `json:"rules,omitempty"`
+	Conditions []ConditionRule `json:"conditions,omitempty"`
+}
+
+// compile validates the rule and compiles the regular expressions.
+func (r *Rule) compile() error {
+	for _, p := range []string{r.Resource, r.Type} {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	switch r.Status {
+	case "", "True", "False", "Unknown":
+	default:
+		return fmt.Errorf("invalid status %q, supported: True, False, Unknown", r.Status)
+	}
+	if r.Severity == 0 {
+		return fmt.Errorf("severity is missing")
+	}
+	var err error
+	if r.Reason != "" {
+		if r.reason, err = regexp.Compile(r.Reason); err != nil {
+			return fmt.Errorf("invalid reason regex: %w", err)
+		}
+	}
+	if r.Message != "" {
+		if r.message, err = regexp.Compile(r.Message); err != nil {
+			return fmt.Errorf("invalid message regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule matches a finding. conditionType is one
+// of the types of a merged finding.
+func (r *Rule) matches(f Finding, conditionType string, now time.Time) bool {
+	if r.Resource != "" && !matchAnyPattern(f.GVR.Resource, []string{r.Resource}) {
+		return false
+	}
+	if r.Type != "" && !matchAnyPattern(conditionType, []string{r.Type}) {
+		return false
+	}
+	if r.Status != "" && r.Status != f.Status {
+		return false
+	}
+	if r.reason != nil && !r.reason.MatchString(f.Reason) {
+		return false
+	}
+	if r.message != nil && !r.message.MatchString(f.Message) {
+		return false
+	}
+	if r.OlderThan != nil {
+		if f.LastTransitionTime.IsZero() || now.Sub(f.LastTransitionTime) <= r.OlderThan.Duration {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadRules reads the severity rules of a rules file, see LoadRulesFile.
+func LoadRules(filename string) ([]Rule, error) {
+	file, err := LoadRulesFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return file.Rules, nil
+}
+
+// LoadRulesFile reads a rules file. "rules" assign severities, "conditions"
+// change the classification of conditions:
+//
+//	rules:
+//	- resource: machinedeployments
+//	  type: Progressing
+//	  status: "False"
+//	  severity: info
+//	conditions:
+//	- group: example.com
+//	  resource: widgets
+//	  type: Synced
+//	  healthy: "True"
+//
+// Unknown fields are an error, so that typos don't go unnoticed.
+func LoadRulesFile(filename string) (*RulesFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules: %w", err)
+	}
+	var file RulesFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing rules %s: %w", filename, err)
+	}
+	for i := range file.Rules {
+		if err := file.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("error in rule %s of %s: %w", ruleName(file.Rules[i].Name, i), filename, err)
+		}
+	}
+	for i := range file.Conditions {
+		if err := file.Conditions[i].compile(); err != nil {
+			return nil, fmt.Errorf("error in condition rule %s of %s: %w", ruleName(file.Conditions[i].Name, i), filename, err)
+		}
+	}
+	return &file, nil
+}
+
+// ruleName returns the name of a rule for error messages, or its position.
+func ruleName(name string, i int) string {
+	if name == "" {
+		return fmt.Sprintf("#%d", i+1)
+	}
+	return name
+}
+
+// builtinRules are checked after the user's rules. The first matching rule
+// wins, defaultSeverity is used if none matches.
+var builtinRules = mustCompileRules([]Rule{
+	// Nodes. Ready=Unknown for some seconds is normal, see --unknown-grace.
+	{Resource: "nodes", Type: "Ready", Status: "Unknown", OlderThan: &metav1.Duration{Duration: 5 * time.Minute}, Severity: SeverityCritical},
+	{Resource: "nodes", Type: "Ready", Status: "Unknown", Severity: SeverityWarning},
+	{Resource: "nodes", Type: "Ready", Severity: SeverityCritical},
+	{Resource: "nodes", Type: "*Pressure", Status: "True", Severity: SeverityError},
+
+	// Resource types which could not be listed.
+	{Type: listErrorType, Severity: SeverityError},
+
+	// Deployments which don't make progress any more.
+	{Type: "Progressing", Status: "False", Reason: "^ProgressDeadlineExceeded$", Severity: SeverityError},
+
+	// Things which failed.
+	{Type: "*Failed", Status: "True", Severity: SeverityError},
+	{Type: "*Failure", Status: "True", Severity: SeverityError},
+	{Reason: `(?i)(failed|error|crashloopbackoff|backofflimitexceeded)`, Severity: SeverityError},
+
+	// Things which are in progress, usually they resolve themselves.
+	{Type: "Progressing", Status: "False", Severity: SeverityInfo},
+	{Type: "Paused", Status: "True", Severity: SeverityInfo},
+	{Type: "Deleting", Status: "True", Severity: SeverityInfo},
+	{Type: "RollingOut", Status: "True", Severity: SeverityInfo},
+	{Type: "ScalingUp", Status: "True", Severity: SeverityInfo},
+	{Type: "ScalingDown", Status: "True", Severity: SeverityInfo},
+})
+
+func mustCompileRules(rules []Rule) []Rule {
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			panic(fmt.Sprintf("builtin rule #%d: %v", i+1, err))
+		}
+	}
+	return rules
+}
+
+// severityOf returns the severity of a finding: The first matching rule of
+// Arguments.Rules, then of builtinRules, wins. conditionType is one of the
+// types of a merged finding.
+func (a *Arguments) severityOf(f Finding, conditionType string) Severity {
+	now := a.now()
+	for _, rules := range [][]Rule{a.Rules, builtinRules} {
+		for i := range rules {
+			if rules[i].matches(f, conditionType, now) {
+				return rules[i].Severity
+			}
+		}
+	}
+	return defaultSeverity
+}
+
+// minSeverity returns Arguments.MinSeverity, at least SeverityInfo.
+func (a *Arguments) minSeverity() Severity {
+	return max(a.MinSeverity, SeverityInfo)
+}
+
+// failOn returns Arguments.FailOn, at least SeverityInfo.
+func (a *Arguments) failOn() Severity {
+	return max(a.FailOn, SeverityInfo)
+}
+
+// unhealthy reports whether a finding has at least the severity of
+// Arguments.FailOn.
+func (c *Counter) unhealthy(args *Arguments) bool {
+	for _, f := range c.Findings {
+		if f.Severity == 0 || f.Severity >= args.failOn() {
+			return true
+		}
+	}
+	return false
+}
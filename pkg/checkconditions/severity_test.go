@@ -0,0 +1,133 @@
+package checkconditions
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseSeverity(t *testing.T) {
+	for i, name := range Severities {
+		s, err := ParseSeverity(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(s) != i+1 || s.String() != name {
+			t.Errorf("ParseSeverity(%q) = %d (%s)", name, s, s)
+		}
+	}
+	if s, err := ParseSeverity("Error"); err != nil || s != SeverityError {
+		t.Errorf("expected case insensitive parsing, got %v, %v", s, err)
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	writeFile(t, valid, `rules:
+- resource: machinedeployments
+  type: Progressing
+  status: "False"
+  severity: info
+- reason: "^Quota"
+  severity: critical
+`)
+	rules, err := LoadRules(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Severity != SeverityInfo || rules[1].Severity != SeverityCritical {
+		t.Fatalf("unexpected rules %+v", rules)
+	}
+	if !rules[1].matches(Finding{GVR: podsGVR, Status: "False", Reason: "QuotaExceeded"}, "Ready", time.Now()) {
+		t.Error("expected reason regex to match")
+	}
+
+	for name, content := range map[string]string{
+		"typo":        "rules:\n- tpye: Ready\n  severity: info\n",
+		"status":      "rules:\n- status: \"false\"\n  severity: info\n",
+		"no-severity": "rules:\n- type: Ready\n",
+		"severity":    "rules:\n- type: Ready\n  severity: fatal\n",
+		"regex":       "rules:\n- reason: \"(\"\n  severity: info\n",
+	} {
+		p := filepath.Join(dir, name+".yaml")
+		writeFile(t, p, content)
+		if _, err := LoadRules(p); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestSeverityOf(t *testing.T) {
+	args := &Arguments{}
+	tests := []struct {
+		resource, conditionType, status, reason string
+		want                                    Severity
+	}{
+		{"nodes", "Ready", "False", "KubeletNotReady", SeverityCritical},
+		{"nodes", "MemoryPressure", "True", "", SeverityError},
+		{"jobs", "Failed", "True", "BackoffLimitExceeded", SeverityError},
+		{"deployments", "Progressing", "False", "ProgressDeadlineExceeded", SeverityError},
+		{"machinedeployments", "Progressing", "False", "NewReplicaSetAvailable", SeverityInfo},
+		{"pods", "Ready", "False", "ContainersNotReady", SeverityWarning},
+		{"machines", deletionTimestampType, "", "", SeverityWarning},
+	}
+	for _, tt := range tests {
+		if got := args.severityOf(Finding{GVR: schema.GroupVersionResource{Resource: tt.resource}, Status: tt.status, Reason: tt.reason}, tt.conditionType); got != tt.want {
+			t.Errorf("%s %s=%s %s: got %s, want %s", tt.resource, tt.conditionType, tt.status, tt.reason, got, tt.want)
+		}
+	}
+
+	// User rules are checked before the builtin rules.
+	args.Rules = []Rule{{Resource: "nodes", Type: "Ready", Severity: SeverityInfo}}
+	if err := args.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := args.severityOf(Finding{GVR: nodesGVR, Status: "False"}, "Ready"); got != SeverityInfo {
+		t.Errorf("expected user rule to win, got %s", got)
+	}
+}
+
+func TestMinSeverityAndFailOn(t *testing.T) {
+	cluster := defaultTestCluster()
+	args := &Arguments{Clients: cluster.clients}
+	counter, err := RunAndGetCounterWithClients(context.Background(), cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := linesContaining(counter.Lines, "node-1"); len(got) != 1 || !strings.HasSuffix(got[0], " [critical]") {
+		t.Fatalf("expected critical node finding, got %v", counter.Lines)
+	}
+	if got := linesContaining(counter.Lines, "[warning]"); len(got) != 3 {
+		t.Fatalf("expected 3 warnings, got %v", counter.Lines)
+	}
+
+	args.FailOn = SeverityCritical
+	if !counter.unhealthy(args) {
+		t.Error("expected unhealthy with --fail-on critical")
+	}
+	args.Rules = []Rule{{Resource: "nodes", Severity: SeverityError}}
+	counter, err = RunAndGetCounterWithClients(context.Background(), cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.unhealthy(args) {
+		t.Errorf("expected healthy with --fail-on critical, got %v", counter.Lines)
+	}
+
+	args.MinSeverity = SeverityError
+	counter, err = RunAndGetCounterWithClients(context.Background(), cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.Lines) != 1 || len(linesContaining(counter.Lines, "node-1 ")) != 1 {
+		t.Errorf("expected only the node finding with --min-severity error, got %v", counter.Lines)
+	}
+}
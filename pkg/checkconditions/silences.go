@@ -0,0 +1,361 @@
+package checkconditions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"sigs.k8s.io/yaml"
+)
+
+// Silence hides matching findings for some time. Namespace, Resource, Name
+// and Type are glob patterns, Reason is a regular expression. Empty fields
+// match everything.
+type Silence struct {
+	ID        string    `json:"id"`
+	Namespace string    `json:"namespace,omitempty"`
+	Resource  string    `json:"resource,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Type      string    `json:"type,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Author    string    `json:"author"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"createdAt"`
+	// ExpiresAt ends the silence. A silence needs ExpiresAt, Window or both.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// Window makes the silence recurring, for example for a maintenance
+	// window every Saturday night.
+	Window *SilenceWindow `json:"window,omitempty"`
+
+	reason *regexp.Regexp
+}
+
+// SilenceWindow is a recurring time window. If End is before Start, the
+// window ends on the next day.
+type SilenceWindow struct {
+	// Days are weekdays like "Sat" or "Saturday". Empty means every day. It
+	// is the day on which the window starts.
+	Days []string `json:"days,omitempty"`
+	// Start and End are times of day like "22:00".
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Timezone is a name like "Europe/Berlin". Empty means local time.
+	Timezone string `json:"timezone,omitempty"`
+
+	days       []time.Weekday
+	start, end time.Duration
+	location   *time.Location
+}
+
+// SilencesFile is the content of the file given via --silences.
+type SilencesFile struct {
+	Silences []Silence `json:"silences"`
+}
+
+// parseWeekday parses a weekday like "Sat" or "Saturday", ignoring case.
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) || strings.EqualFold(s, d.String()[:3]) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// compile validates the silence and compiles the patterns.
+func (s *Silence) compile() error {
+	for _, p := range []string{s.Namespace, s.Resource, s.Name, s.Type} {
+		if err := validatePatterns([]string{p}); err != nil {
+			return err
+		}
+	}
+	if s.Author == "" {
+		return errors.New("author is missing")
+	}
+	if s.Comment == "" {
+		return errors.New("comment is missing")
+	}
+	if s.ExpiresAt == nil && s.Window == nil {
+		return errors.New("expiresAt or window is needed")
+	}
+	if s.Reason != "" {
+		var err error
+		if s.reason, err = regexp.Compile(s.Reason); err != nil {
+			return fmt.Errorf("invalid reason regex: %w", err)
+		}
+	}
+	if s.Window != nil {
+		if err := s.Window.compile(); err != nil {
+			return fmt.Errorf("invalid window: %w", err)
+		}
+	}
+	return nil
+}
+
+func (w *SilenceWindow) compile() error {
+	var err error
+	if w.start, err = parseTimeOfDay(w.Start); err != nil {
+		return err
+	}
+	if w.end, err = parseTimeOfDay(w.End); err != nil {
+		return err
+	}
+	w.location = time.Local
+	if w.Timezone != "" {
+		if w.location, err = time.LoadLocation(w.Timezone); err != nil {
+			return err
+		}
+	}
+	w.days = nil
+	for _, d := range w.Days {
+		day, ok := parseWeekday(d)
+		if !ok {
+			return fmt.Errorf("invalid day %q, supported: Mon, Tue, ... or Monday, Tuesday, ...", d)
+		}
+		w.days = append(w.days, day)
+	}
+	return nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// active reports whether now is inside the window.
+func (w *SilenceWindow) active(now time.Time) bool {
+	t := now.In(w.location)
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	day := t.Weekday()
+	switch {
+	case w.start <= w.end:
+		if sinceMidnight < w.start || sinceMidnight >= w.end {
+			return false
+		}
+	case sinceMidnight >= w.start:
+		// Before midnight of a window which ends on the next day.
+	case sinceMidnight < w.end:
+		// After midnight, the window started yesterday.
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+	return len(w.days) == 0 || slices.Contains(w.days, day)
+}
+
+// expired reports whether the silence ended before now.
+func (s *Silence) expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+// active reports whether the silence hides findings at the given time.
+func (s *Silence) active(now time.Time) bool {
+	if s.expired(now) {
+		return false
+	}
+	return s.Window == nil || s.Window.active(now)
+}
+
+// matches reports whether the silence matches a finding. A merged finding
+// is only matched if the silence matches all of its types.
+func (s *Silence) matches(f Finding, types []string) bool {
+	for _, m := range []struct{ pattern, value string }{
+		{s.Namespace, f.Namespace},
+		{s.Resource, f.GVR.Resource},
+		{s.Name, f.Name},
+	} {
+		if m.pattern != "" && !matchAnyPattern(m.value, []string{m.pattern}) {
+			return false
+		}
+	}
+	if s.Type != "" {
+		for _, t := range types {
+			if !matchAnyPattern(t, []string{s.Type}) {
+				return false
+			}
+		}
+	}
+	return s.reason == nil || s.reason.MatchString(f.Reason)
+}
+
+// LoadSilences reads a silences file. A missing file contains no silences,
+// so that --silences can be set before the first "silence add".
+func LoadSilences(filename string) ([]Silence, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading silences: %w", err)
+	}
+	var file SilencesFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing silences %s: %w", filename, err)
+	}
+	for i := range file.Silences {
+		if err := file.Silences[i].compile(); err != nil {
+			return nil, fmt.Errorf("error in silence %s of %s: %w", file.Silences[i].ID, filename, err)
+		}
+	}
+	return file.Silences, nil
+}
+
+// SaveSilences writes the silences file atomically. The file keeps its mode,
+// a new file gets 0644 like a rules file.
+func SaveSilences(filename string, silences []Silence) error {
+	data, err := yaml.Marshal(SilencesFile{Silences: silences})
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(filename); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// AddSilence validates the silence, gives it an ID and appends it to the
+// silences file.
+func AddSilence(filename string, s Silence, now time.Time) (Silence, error) {
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = now.UTC().Truncate(time.Second)
+	}
+	if err := s.compile(); err != nil {
+		return s, err
+	}
+	silences, err := LoadSilences(filename)
+	if err != nil {
+		return s, err
+	}
+	for s.ID == "" || slices.ContainsFunc(silences, func(o Silence) bool { return o.ID == s.ID }) {
+		s.ID, err = newSilenceID()
+		if err != nil {
+			return s, err
+		}
+	}
+	return s, SaveSilences(filename, append(silences, s))
+}
+
+func newSilenceID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ExpireSilence sets the expiry of the silence with the given ID to now.
+func ExpireSilence(filename, id string, now time.Time) error {
+	silences, err := LoadSilences(filename)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(silences, func(s Silence) bool { return s.ID == id })
+	if i < 0 {
+		return fmt.Errorf("silence %q not found in %s", id, filename)
+	}
+	expiresAt := now.UTC().Truncate(time.Second)
+	silences[i].ExpiresAt = &expiresAt
+	return SaveSilences(filename, silences)
+}
+
+// WriteSilences lists the silences and their state at the given time.
+func WriteSilences(w io.Writer, silences []Silence, now time.Time) {
+	for _, s := range silences {
+		state := "inactive"
+		switch {
+		case s.expired(now):
+			state = "expired"
+		case s.active(now):
+			state = "active"
+		}
+		var matcher []string
+		for _, m := range []struct{ key, value string }{
+			{"namespace", s.Namespace}, {"resource", s.Resource}, {"name", s.Name},
+			{"type", s.Type}, {"reason", s.Reason},
+		} {
+			if m.value != "" {
+				matcher = append(matcher, fmt.Sprintf("%s=%s", m.key, m.value))
+			}
+		}
+		if len(matcher) == 0 {
+			matcher = append(matcher, "everything")
+		}
+		until := ""
+		if s.ExpiresAt != nil {
+			until = " until " + s.ExpiresAt.Format(time.RFC3339)
+		}
+		if s.Window != nil {
+			days := "daily"
+			if len(s.Window.Days) > 0 {
+				days = strings.Join(s.Window.Days, ",")
+			}
+			until += fmt.Sprintf(" window %s %s-%s", days, s.Window.Start, s.Window.End)
+		}
+		fmt.Fprintf(w, "%s %s %s%s by %s: %s\n", s.ID, state, strings.Join(matcher, " "), until, s.Author, s.Comment)
+	}
+}
+
+// loadSilences reads Arguments.SilencesFile.
+func (a *Arguments) loadSilences() error {
+	a.silences = nil
+	if a.SilencesFile == "" {
+		return nil
+	}
+	silences, err := LoadSilences(a.SilencesFile)
+	if err != nil {
+		return err
+	}
+	a.silences = silences
+	return nil
+}
+
+// silenced reports whether an active silence matches the finding.
+func (a *Arguments) silenced(f Finding, types []string) bool {
+	now := a.now()
+	for i := range a.silences {
+		if a.silences[i].active(now) && a.silences[i].matches(f, types) {
+			return true
+		}
+	}
+	return false
+}
+
+// printExpiredSilences prints one line per expired silence, so that they
+// get cleaned up.
+func (a *Arguments) printExpiredSilences(w io.Writer) {
+	now := a.now()
+	for _, s := range a.silences {
+		if s.expired(now) {
+			fmt.Fprintf(w, "Silence %s by %s expired %s ago: %s\n", s.ID, s.Author,
+				now.Sub(*s.ExpiresAt).Round(time.Minute), s.Comment)
+		}
+	}
+}
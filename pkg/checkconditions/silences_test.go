@@ -0,0 +1,155 @@
+package checkconditions
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSilenceWindow(t *testing.T) {
+	w := &SilenceWindow{Days: []string{"Sat"}, Start: "22:00", End: "04:00", Timezone: "UTC"}
+	if err := w.compile(); err != nil {
+		t.Fatal(err)
+	}
+	// 2024-05-04 is a Saturday.
+	tests := []struct {
+		time   string
+		active bool
+	}{
+		{"2024-05-04T21:59:00Z", false},
+		{"2024-05-04T22:00:00Z", true},
+		{"2024-05-05T03:59:00Z", true}, // Sunday, but the window started on Saturday.
+		{"2024-05-05T04:00:00Z", false},
+		{"2024-05-05T22:30:00Z", false}, // Sunday
+		{"2024-05-04T02:00:00Z", false}, // started on Friday
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse(time.RFC3339, tt.time)
+		if got := w.active(now); got != tt.active {
+			t.Errorf("%s: got %v, want %v", tt.time, got, tt.active)
+		}
+	}
+
+	for _, valid := range []string{"sat", "SAT", "Saturday", "saturday"} {
+		w := &SilenceWindow{Days: []string{valid}, Start: "22:00", End: "04:00"}
+		if err := w.compile(); err != nil || len(w.days) != 1 || w.days[0] != time.Saturday {
+			t.Errorf("%s: got %v, %v, want Saturday", valid, w.days, err)
+		}
+	}
+
+	for _, invalid := range []SilenceWindow{
+		{Start: "22", End: "04:00"},
+		{Start: "22:00", End: "04:00", Days: []string{"Caturday"}},
+		{Start: "22:00", End: "04:00", Days: []string{"Saturnday"}},
+		{Start: "22:00", End: "04:00", Days: []string{"Monxyz"}},
+		{Start: "22:00", End: "04:00", Days: []string{"Sa"}},
+		{Start: "22:00", End: "04:00", Timezone: "Nowhere/City"},
+	} {
+		if err := invalid.compile(); err == nil {
+			t.Errorf("expected error for %+v", invalid)
+		}
+	}
+}
+
+func TestAddAndExpireSilence(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "silences.yaml")
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Hour)
+
+	if _, err := AddSilence(filename, Silence{Author: "alice", ExpiresAt: &expiresAt}, now); err == nil {
+		t.Fatal("expected error for a silence without comment")
+	}
+	s, err := AddSilence(filename, Silence{Namespace: "team-*", Type: "Ready", Reason: "^Test", Author: "alice", Comment: "known", ExpiresAt: &expiresAt}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID == "" {
+		t.Fatal("expected an ID")
+	}
+	silences, err := LoadSilences(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(silences) != 1 || !silences[0].active(now) || silences[0].CreatedAt != now {
+		t.Fatalf("unexpected silences %+v", silences)
+	}
+
+	if err := ExpireSilence(filename, "unknown", now); err == nil {
+		t.Error("expected error for unknown ID")
+	}
+	if err := ExpireSilence(filename, s.ID, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	silences, err = LoadSilences(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	WriteSilences(&buf, silences, now.Add(2*time.Minute))
+	if !strings.HasPrefix(buf.String(), s.ID+" expired namespace=team-* type=Ready reason=^Test until ") {
+		t.Errorf("unexpected list: %s", buf.String())
+	}
+
+	fi, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o644 {
+		t.Errorf("new silences file should have mode 0644, got %v", fi.Mode().Perm())
+	}
+	if err := os.Chmod(filename, 0o664); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveSilences(filename, silences); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err = os.Stat(filename); err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o664 {
+		t.Errorf("silences file should keep its mode 0664, got %v", fi.Mode().Perm())
+	}
+
+	if silences, err := LoadSilences(filepath.Join(t.TempDir(), "missing.yaml")); err != nil || len(silences) != 0 {
+		t.Errorf("expected no silences for a missing file, got %v, %v", silences, err)
+	}
+}
+
+func TestSilencesHideFindings(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "silences.yaml")
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-2 * time.Hour)
+	for _, s := range []Silence{
+		{Namespace: "team-a", Resource: "pods", Type: "Ready", Reason: "^Testing$", Author: "alice", Comment: "known", ExpiresAt: &later},
+		{Resource: "nodes", Author: "bob", Comment: "old", ExpiresAt: &earlier},
+	} {
+		if _, err := AddSilence(filename, s, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cluster := defaultTestCluster()
+	args := &Arguments{SilencesFile: filename}
+	counter, err := RunAndGetCounterWithClients(context.Background(), cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := linesContaining(counter.Lines, "pod-a"); len(got) != 0 {
+		t.Errorf("expected pod-a to be silenced, got %v", got)
+	}
+	if len(counter.Lines) != 3 || counter.SilencedFindings != 1 {
+		t.Errorf("expected 3 lines and 1 silenced finding, got %d: %v", counter.SilencedFindings, counter.Lines)
+	}
+
+	var buf bytes.Buffer
+	args.printExpiredSilences(&buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "Silence ") || !strings.HasSuffix(lines[0], " by bob expired 2h0m0s ago: old") {
+		t.Errorf("unexpected notice: %q", buf.String())
+	}
+}
@@ -0,0 +1,254 @@
+package checkconditions
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// A snapshot is a gzipped tar archive:
+//
+//	metadata.json                                capture time and discovery result
+//	resources/<group>/<version>/<resource>.json  one "List" per resource type
+//
+// The core group is stored as "core". The resource files are plain "List"
+// objects, so an extracted snapshot can be checked with check-files, too.
+const (
+	snapshotMetadataFile  = "metadata.json"
+	snapshotResourcesDir  = "resources"
+	snapshotCoreGroup     = "core"
+	snapshotFormatVersion = 1
+)
+
+type snapshotMetadata struct {
+	FormatVersion int       `json:"formatVersion"`
+	CaptureTime   time.Time `json:"captureTime"`
+	// Namespaces is the resolved -n filter of the capture. Empty means all.
+	Namespaces         []string                  `json:"namespaces,omitempty"`
+	OnlyWithConditions bool                      `json:"onlyWithConditions,omitempty"`
+	Resources          []*metav1.APIResourceList `json:"resources"`
+}
+
+// RunSnapshot scans the cluster like "all" and writes every listed object to
+// a snapshot archive at path. With onlyWithConditions, objects without
+// conditions and without deletionTimestamp are left out, since they can't
+// produce findings.
+func RunSnapshot(ctx context.Context, args *Arguments, path string, onlyWithConditions bool) error {
+	if err := args.rejectOutputFlags("snapshot"); err != nil {
+		return err
+	}
+	clients, err := args.clients()
+	if err != nil {
+		return err
+	}
+	args.keepLists = true
+	defer func() { args.keepLists = false }()
+
+	captureTime := time.Now().UTC().Truncate(time.Second)
+	counter, err := runWithRetries(ctx, clients, args)
+	if err != nil {
+		return err
+	}
+	meta := snapshotMetadata{
+		FormatVersion:      snapshotFormatVersion,
+		CaptureTime:        captureTime,
+		Namespaces:         args.Namespaces,
+		OnlyWithConditions: onlyWithConditions,
+		Resources:          counter.serverResources,
+	}
+
+	// Write to a temporary file first, so that an interrupted run does not
+	// leave a truncated archive behind.
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("error creating snapshot: %w", err)
+	}
+	objects, err := writeSnapshot(f, meta, counter.lists)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing snapshot %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing snapshot %s: %w", path, err)
+	}
+	fmt.Printf("Wrote snapshot %s: %d objects of %d resource types. Captured at %s\n",
+		path, objects, len(counter.lists), captureTime.Format(time.RFC3339))
+	return nil
+}
+
+// RunFromSnapshot checks the objects of a snapshot archive like "all" checks
+// a cluster. Durations are relative to the capture time, unless args.Now is
+// set. It returns true if an unhealthy condition was found.
+func RunFromSnapshot(args *Arguments, path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("error opening snapshot: %w", err)
+	}
+	defer f.Close()
+	meta, lists, err := readSnapshot(f)
+	if err != nil {
+		return false, fmt.Errorf("error reading snapshot %s: %w", path, err)
+	}
+	if args.Now.IsZero() {
+		args.Now = meta.CaptureTime
+	}
+	if !args.namespaceFilterActive() {
+		// Report the scope of the capture in the summary line.
+		args.Namespaces = meta.Namespaces
+	}
+	counter, err := checkResourceLists(args, lists)
+	if err != nil {
+		return false, err
+	}
+	return printCounter(args, &counter)
+}
+
+func writeSnapshot(w io.Writer, meta snapshotMetadata, lists []resourceList) (int, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	addFile := func(name string, v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		err = tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: meta.CaptureTime,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	}
+
+	if err := addFile(snapshotMetadataFile, meta); err != nil {
+		return 0, err
+	}
+
+	// Stable order, so that two snapshots of the same state are equal.
+	slices.SortFunc(lists, func(a, b resourceList) int {
+		return strings.Compare(a.gvr.String(), b.gvr.String())
+	})
+	objects := 0
+	for _, rl := range lists {
+		items := make([]interface{}, 0, len(rl.list.Items))
+		for _, obj := range rl.list.Items {
+			if meta.OnlyWithConditions && !hasConditionsOrDeletionTimestamp(rl.gvr, obj) {
+				continue
+			}
+			items = append(items, obj.Object)
+		}
+		if len(items) == 0 && meta.OnlyWithConditions {
+			continue
+		}
+		list := map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"items":      items,
+		}
+		if err := addFile(snapshotResourceFile(rl.gvr), list); err != nil {
+			return 0, err
+		}
+		objects += len(items)
+	}
+	if err := tw.Close(); err != nil {
+		return 0, err
+	}
+	return objects, gz.Close()
+}
+
+func readSnapshot(r io.Reader) (snapshotMetadata, []resourceList, error) {
+	var meta snapshotMetadata
+	var lists []resourceList
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return meta, nil, err
+	}
+	tr := tar.NewReader(gz)
+	foundMetadata := false
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return meta, nil, err
+		}
+		if hdr.Name == snapshotMetadataFile {
+			if err := json.NewDecoder(tr).Decode(&meta); err != nil {
+				return meta, nil, fmt.Errorf("error decoding %s: %w", hdr.Name, err)
+			}
+			if meta.FormatVersion != snapshotFormatVersion {
+				return meta, nil, fmt.Errorf("unsupported snapshot format version %d", meta.FormatVersion)
+			}
+			foundMetadata = true
+			continue
+		}
+		gvr, ok := parseSnapshotResourceFile(hdr.Name)
+		if !ok {
+			continue
+		}
+		objects, err := decodeObjects(tr, hdr.Name)
+		if err != nil {
+			return meta, nil, err
+		}
+		lists = append(lists, resourceList{gvr: gvr, list: &unstructured.UnstructuredList{Items: objects}})
+	}
+	if !foundMetadata {
+		return meta, nil, fmt.Errorf("%s is missing, not a snapshot", snapshotMetadataFile)
+	}
+	return meta, lists, nil
+}
+
+func snapshotResourceFile(gvr schema.GroupVersionResource) string {
+	group := gvr.Group
+	if group == "" {
+		group = snapshotCoreGroup
+	}
+	return filepath.ToSlash(filepath.Join(snapshotResourcesDir, group, gvr.Version, gvr.Resource+".json"))
+}
+
+func parseSnapshotResourceFile(name string) (schema.GroupVersionResource, bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 4 || parts[0] != snapshotResourcesDir || !strings.HasSuffix(parts[3], ".json") {
+		return schema.GroupVersionResource{}, false
+	}
+	group := parts[1]
+	if group == snapshotCoreGroup {
+		group = ""
+	}
+	return schema.GroupVersionResource{
+		Group:    group,
+		Version:  parts[2],
+		Resource: strings.TrimSuffix(parts[3], ".json"),
+	}, true
+}
+
+func hasConditionsOrDeletionTimestamp(gvr schema.GroupVersionResource, obj unstructured.Unstructured) bool {
+	if obj.GetDeletionTimestamp() != nil {
+		return true
+	}
+	conditions, _, _ := conditionsOf(gvr, obj)
+	return len(conditions) > 0
+}
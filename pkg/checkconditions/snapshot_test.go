@@ -0,0 +1,141 @@
+package checkconditions
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	c := defaultTestCluster()
+	path := filepath.Join(t.TempDir(), "snap.tar.gz")
+	args := &Arguments{Clients: c.clients}
+	if err := RunSnapshot(context.Background(), args, path, false); err != nil {
+		t.Fatal(err)
+	}
+	if args.keepLists {
+		t.Error("keepLists should be reset after the snapshot")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	meta, lists, err := readSnapshot(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.CaptureTime.IsZero() {
+		t.Error("capture time missing")
+	}
+	if len(meta.Resources) != 2 {
+		t.Errorf("expected discovery metadata of 2 group versions, got %d", len(meta.Resources))
+	}
+
+	live, err := RunAndGetCounterWithClients(context.Background(), c.clients, &Arguments{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := checkResourceLists(&Arguments{Now: meta.CaptureTime}, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(live.Lines, replayed.Lines) {
+		t.Fatalf("live:\n%s\nreplayed:\n%s", strings.Join(live.Lines, "\n"), strings.Join(replayed.Lines, "\n"))
+	}
+	if live.CheckedResources != replayed.CheckedResources {
+		t.Errorf("checked resources differ: %d vs %d", live.CheckedResources, replayed.CheckedResources)
+	}
+}
+
+func TestSnapshotOnlyWithConditions(t *testing.T) {
+	captureTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	withCondition := newTestObject(podsGVR, "Pod", "default", "with", map[string]interface{}{
+		"type":               "Ready",
+		"status":             "False",
+		"lastTransitionTime": "2024-05-01T09:45:00Z",
+	})
+	without := newTestObject(podsGVR, "Pod", "default", "without")
+	lists := []resourceList{
+		{gvr: podsGVR, list: &unstructured.UnstructuredList{Items: []unstructured.Unstructured{*withCondition, *without}}},
+		{gvr: widgetsGVR, list: &unstructured.UnstructuredList{Items: []unstructured.Unstructured{*newTestObject(widgetsGVR, "Widget", "default", "plain")}}},
+	}
+	var buf bytes.Buffer
+	n, err := writeSnapshot(&buf, snapshotMetadata{
+		FormatVersion:      snapshotFormatVersion,
+		CaptureTime:        captureTime,
+		OnlyWithConditions: true,
+	}, lists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 object, got %d", n)
+	}
+	meta, readLists, err := readSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(readLists) != 1 || readLists[0].gvr != podsGVR {
+		t.Fatalf("expected only the pods list, got %+v", readLists)
+	}
+
+	// Durations are relative to the capture time, not to the time of the replay.
+	counter, err := checkResourceLists(&Arguments{Now: meta.CaptureTime}, readLists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.Lines) != 1 || !strings.Contains(counter.Lines[0], "(15m0s)") {
+		t.Fatalf("expected duration relative to capture time, got %v", counter.Lines)
+	}
+}
+
+func TestRunSnapshotRejectsOutputFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snap.tar.gz")
+	for _, args := range []*Arguments{{Stream: true}, {Priority: true}, {Graph: "dot"}, {GroupBy: GroupByOwner}, {BaselineFile: "baseline.json"}, {WriteBaselineFile: "baseline.json"}} {
+		if err := RunSnapshot(context.Background(), args, path, false); err == nil || !strings.Contains(err.Error(), "can't be combined") {
+			t.Errorf("%+v: expected an error, got %v", args, err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("no snapshot should be written, got %v", err)
+	}
+}
+
+func TestReadSnapshotRejectsOtherArchives(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := writeSnapshot(&buf, snapshotMetadata{FormatVersion: 99}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := readSnapshot(&buf); err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Fatalf("expected version error, got %v", err)
+	}
+}
+
+func TestSnapshotResourceFile(t *testing.T) {
+	for _, tc := range []struct {
+		gvr  schema.GroupVersionResource
+		file string
+	}{
+		{podsGVR, "resources/core/v1/pods.json"},
+		{widgetsGVR, "resources/example.com/v1/widgets.json"},
+	} {
+		name := snapshotResourceFile(tc.gvr)
+		if name != tc.file {
+			t.Errorf("expected %s, got %s", tc.file, name)
+		}
+		parsed, ok := parseSnapshotResourceFile(name)
+		if !ok || parsed != tc.gvr {
+			t.Errorf("round trip of %s failed: %v", name, parsed)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package checkconditions
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// staleType is the type of findings about objects whose status was not
+// written for the current generation, see Arguments.StaleAfter.
+const staleType = "Stale"
+
+// staleFinding reports an object whose status.observedGeneration, or the
+// observedGeneration of one of its conditions, is lower than
+// metadata.generation for longer than Arguments.StaleAfter. That usually
+// means the controller does not reconcile the object.
+func staleFinding(args *Arguments, gvr schema.GroupVersionResource, obj unstructured.Unstructured, conditions []interface{}) (Finding, bool) {
+	generation, _ := nestedInt64(obj.Object, "metadata", "generation")
+	if generation == 0 || obj.GetDeletionTimestamp() != nil {
+		return Finding{}, false
+	}
+	var lagging []string
+	observed, ok := nestedInt64(obj.Object, "status", "observedGeneration")
+	if ok && observed < generation {
+		lagging = append(lagging, fmt.Sprintf("status.observedGeneration %d", observed))
+	}
+	for _, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		observed, ok := nestedInt64(conditionMap, "observedGeneration")
+		if !ok || observed >= generation {
+			continue
+		}
+		conditionType, _ := conditionMap["type"].(string)
+		lagging = append(lagging, fmt.Sprintf("condition %s %d", conditionType, observed))
+	}
+	if len(lagging) == 0 {
+		return Finding{}, false
+	}
+	since := specChangedAt(obj)
+	age := args.now().Sub(since)
+	if age <= args.StaleAfter {
+		return Finding{}, false
+	}
+	f := newFinding(gvr, obj, fmt.Sprintf("Stale: observedGeneration of %s < generation %d (%s)",
+		strings.Join(lagging, ", "), generation, age.Round(time.Second)))
+	f.Type = staleType
+	f.LastTransitionTime = since
+	return f, true
+}
+
+// specChangedAt returns when the spec was changed the last time: the newest
+// managedFields entry which is not about the status subresource. Without
+// managedFields (for example "kubectl get -o yaml" removes them) it is the
+// creationTimestamp.
+func specChangedAt(obj unstructured.Unstructured) time.Time {
+	var changed time.Time
+	for _, m := range obj.GetManagedFields() {
+		if m.Subresource != "" || m.Time == nil {
+			continue
+		}
+		if m.FieldsV1 != nil && !strings.Contains(string(m.FieldsV1.Raw), `"f:spec"`) {
+			continue
+		}
+		if m.Time.After(changed) {
+			changed = m.Time.Time
+		}
+	}
+	if changed.IsZero() {
+		changed = obj.GetCreationTimestamp().Time
+	}
+	return changed
+}
+
+// nestedInt64 reads a number. Objects decoded from files contain float64
+// instead of int64.
+func nestedInt64(obj map[string]interface{}, fields ...string) (int64, bool) {
+	v, found, err := unstructured.NestedFieldNoCopy(obj, fields...)
+	if !found || err != nil {
+		return 0, false
+	}
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
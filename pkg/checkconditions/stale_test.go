@@ -0,0 +1,93 @@
+package checkconditions
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const staleMachinesYAML = `apiVersion: v1
+kind: List
+items:
+- apiVersion: cluster.x-k8s.io/v1beta1
+  kind: Machine
+  metadata:
+    name: stale-status
+    namespace: default
+    generation: 3
+    creationTimestamp: "2024-05-01T08:00:00Z"
+  status:
+    observedGeneration: 2
+    conditions:
+    - type: Ready
+      status: "True"
+      observedGeneration: 2
+    - type: InfrastructureReady
+      status: "True"
+      observedGeneration: 3
+- apiVersion: cluster.x-k8s.io/v1beta1
+  kind: Machine
+  metadata:
+    name: up-to-date
+    namespace: default
+    generation: 3
+    creationTimestamp: "2024-05-01T08:00:00Z"
+  status:
+    observedGeneration: 3
+- apiVersion: cluster.x-k8s.io/v1beta1
+  kind: Machine
+  metadata:
+    name: no-observed-generation
+    namespace: default
+    generation: 3
+    creationTimestamp: "2024-05-01T08:00:00Z"
+`
+
+func TestStale(t *testing.T) {
+	objects, err := ReadObjects([]string{StdinPath}, strings.NewReader(staleMachinesYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := &Arguments{
+		Now:        time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		StaleAfter: 10 * time.Minute,
+	}
+	counter, err := checkResourceLists(args, groupByResource(objects))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"  default machines stale-status Stale: observedGeneration of status.observedGeneration 2, condition Ready 2 < generation 3 (2h0m0s) [warning]",
+	}
+	if !slices.Equal(counter.Lines, want) {
+		t.Fatalf("unexpected lines:\n%s", strings.Join(counter.Lines, "\n"))
+	}
+
+	// The spec was changed 5 minutes ago, that's within --stale-after.
+	specUpdate := metav1.NewTime(args.Now.Add(-5 * time.Minute))
+	statusUpdate := metav1.NewTime(args.Now.Add(-time.Minute))
+	objects[0].SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: "kubectl", Time: &specUpdate, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{}}`)}},
+		{Manager: "controller", Time: &statusUpdate, Subresource: "status"},
+	})
+	counter, err = checkResourceLists(args, groupByResource(objects))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.Lines) != 0 {
+		t.Errorf("expected no finding within --stale-after, got %v", counter.Lines)
+	}
+
+	args.StaleAfter = 0
+	counter, err = checkResourceLists(args, groupByResource([]unstructured.Unstructured{objects[0]}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.Lines) != 0 {
+		t.Errorf("expected no finding with --stale-after 0, got %v", counter.Lines)
+	}
+}
@@ -0,0 +1,53 @@
+package checkconditions
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// progress writes "Checked n of m resource types" to a terminal (usually
+// stderr) while a scan is running, see Arguments.Progress. The line is
+// overwritten with each update and cleared before findings are printed.
+type progress struct {
+	mu    sync.Mutex
+	w     io.Writer
+	total int
+	done  int
+}
+
+func newProgress(w io.Writer, total int) *progress {
+	return &progress{w: w, total: total}
+}
+
+// update is called when a resource type was checked.
+func (p *progress) update(output handleResourceTypeOutput) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	fmt.Fprintf(p.w, "\r\033[KChecked %d of %d resource types, last: %s", p.done, p.total, output.gvr.GroupResource())
+}
+
+// clear removes the progress line.
+func (p *progress) clear() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprint(p.w, "\r\033[K")
+}
+
+// printsEarly reports whether the findings of a resource type are printed
+// as soon as it was checked: with Arguments.Stream all of them, with
+// Arguments.Priority the prioritized ones. Grouped output, graphs and
+// baseline diffs are only printed at the end.
+func (a *Arguments) printsEarly(o handleResourceTypeOutput) bool {
+	if a.GroupBy != "" || a.Graph != "" || a.BaselineFile != "" {
+		return false
+	}
+	return a.Stream || o.prioritized
+}
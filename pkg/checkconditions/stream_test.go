@@ -0,0 +1,51 @@
+package checkconditions
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	cluster := defaultTestCluster()
+	args := &Arguments{Stream: true}
+	counter, err := RunAndGetCounterWithClients(context.Background(), cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counter.earlyLines) != 4 {
+		t.Fatalf("expected all lines to be printed early, got %v", counter.earlyLines)
+	}
+	for _, line := range counter.Lines {
+		if counter.earlyLines[line] != 1 {
+			t.Errorf("expected %q to be printed early once", line)
+		}
+	}
+
+	args = &Arguments{Stream: true, GroupBy: GroupByOwner}
+	if _, err := RunAndGetCounterWithClients(context.Background(), cluster.clients, args); err == nil {
+		t.Error("expected --stream and --group-by to be rejected")
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgress(&buf, 2)
+	p.update(handleResourceTypeOutput{gvr: podsGVR})
+	p.update(handleResourceTypeOutput{gvr: widgetsGVR})
+	p.clear()
+	for _, s := range []string{"Checked 1 of 2 resource types, last: pods", "Checked 2 of 2 resource types, last: widgets.example.com"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("expected %q in %q", s, buf.String())
+		}
+	}
+	if !strings.HasSuffix(buf.String(), "\r\033[K") {
+		t.Errorf("expected the line to be cleared, got %q", buf.String())
+	}
+
+	// Without --progress nothing is written.
+	var none *progress
+	none.update(handleResourceTypeOutput{gvr: podsGVR})
+	none.clear()
+}
@@ -0,0 +1,257 @@
+package checkconditions
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// AnnotationExpect is set on the fixture objects of "test-rules": a
+// comma-separated list of condition types with the expected verdict, for
+// example "Ready=unhealthy,Synced=healthy". Verdicts are healthy, unhealthy
+// and skipped.
+const AnnotationExpect = "check-conditions.guettli.de/expect"
+
+// expectFileSuffix is the suffix of the sidecar files of fixtures. The
+// expectations of machines.yaml are in machines.expect.yaml:
+//
+//	Machine/default/machine-1:
+//	  Ready: unhealthy
+//	  InfrastructureReady: healthy
+//
+// The keys are Kind/namespace/name, or Kind/name for cluster-scoped objects.
+const expectFileSuffix = ".expect.yaml"
+
+var verdicts = []string{verdictHealthy, verdictUnhealthy, verdictSkipped}
+
+// ruleTestMismatch is a condition whose verdict differs from the expected
+// one. got is empty if the object has no such condition.
+type ruleTestMismatch struct {
+	object        string
+	conditionType string
+	line          string
+	got, want     string
+	// step decided about got, see Arguments.explainCondition.
+	step string
+}
+
+func (m ruleTestMismatch) String() string {
+	if m.got == "" {
+		return fmt.Sprintf("%s: condition %s is missing, want %s", m.object, m.conditionType, m.want)
+	}
+	return fmt.Sprintf("%s: got %s (%s), want %s: %s", m.object, m.got, m.step, m.want, m.line)
+}
+
+// ruleTestFile is the result of one fixture file.
+type ruleTestFile struct {
+	checked    int
+	unexpected int
+	mismatches []ruleTestMismatch
+}
+
+// RunTestRules classifies the conditions of the fixture objects in paths
+// (files or directories, see ReadObjects) with the builtin rules and the
+// rules of rulesFile, and compares the verdicts with the expectations, see
+// AnnotationExpect and expectFileSuffix. It writes one line per file and the
+// mismatches to w, and returns true if the rules file is invalid or a
+// condition has an unexpected verdict.
+func RunTestRules(args *Arguments, rulesFile string, paths []string, w io.Writer) (bool, error) {
+	if rulesFile != "" {
+		file, err := LoadRulesFile(rulesFile)
+		if err != nil {
+			fmt.Fprintf(w, "FAIL  %s\n    --- %v\n", rulesFile, err)
+			return true, nil
+		}
+		fmt.Fprintf(w, "ok    %s  %d rules, %d condition rules\n", rulesFile, len(file.Rules), len(file.Conditions))
+		args.Rules = file.Rules
+		args.ConditionRules = file.Conditions
+	}
+	var files []string
+	for _, p := range paths {
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || strings.HasSuffix(path, expectFileSuffix) || (path != p && !isManifestFile(path)) {
+				return nil
+			}
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	if len(files) == 0 {
+		return false, errors.New("no fixture files found")
+	}
+	var failed, checked, unexpected int
+	for _, path := range files {
+		result, err := testRulesFile(args, path)
+		if err != nil {
+			return false, err
+		}
+		status := "ok  "
+		if len(result.mismatches) > 0 {
+			status = "FAIL"
+			failed += len(result.mismatches)
+		}
+		fmt.Fprintf(w, "%s  %s  %d conditions\n", status, path, result.checked)
+		for _, m := range result.mismatches {
+			fmt.Fprintf(w, "    --- %s\n", m)
+		}
+		checked += result.checked
+		unexpected += result.unexpected
+	}
+	summary := fmt.Sprintf("%d conditions in %d files", checked, len(files))
+	if unexpected > 0 {
+		summary += fmt.Sprintf(", %d conditions without expectation", unexpected)
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "FAIL: %d mismatches, %s\n", failed, summary)
+		return true, nil
+	}
+	fmt.Fprintf(w, "PASS: %s\n", summary)
+	return false, nil
+}
+
+func testRulesFile(args *Arguments, path string) (ruleTestFile, error) {
+	var result ruleTestFile
+	f, err := os.Open(path)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+	objects, err := decodeObjects(f, path)
+	if err != nil {
+		return result, err
+	}
+	sidecar, err := readExpectFile(strings.TrimSuffix(path, filepath.Ext(path)) + expectFileSuffix)
+	if err != nil {
+		return result, err
+	}
+	for _, obj := range objects {
+		key := fixtureKey(obj)
+		expect, err := parseExpectAnnotation(obj.GetAnnotations()[AnnotationExpect])
+		if err != nil {
+			return result, fmt.Errorf("%s %s: %w", path, key, err)
+		}
+		if e, ok := sidecar[key]; ok {
+			for conditionType, verdict := range e {
+				expect[conditionType] = verdict
+			}
+			delete(sidecar, key)
+		}
+		gvr, _ := meta.UnsafeGuessKindToResource(obj.GroupVersionKind())
+		conditions, _, err := conditionsOf(gvr, obj)
+		if err != nil {
+			return result, fmt.Errorf("%s %s: invalid conditions: %w", path, key, err)
+		}
+		for _, condition := range conditions {
+			conditionMap, ok := condition.(map[string]interface{})
+			if !ok {
+				return result, fmt.Errorf("%s %s: invalid condition %v", path, key, condition)
+			}
+			conditionType, _ := conditionMap["type"].(string)
+			conditionStatus, _ := conditionMap["status"].(string)
+			conditionReason, _ := conditionMap["reason"].(string)
+			conditionMessage, _ := conditionMap["message"].(string)
+			want, ok := expect[conditionType]
+			if !ok {
+				result.unexpected++
+				continue
+			}
+			delete(expect, conditionType)
+			result.checked++
+			got := args.explainCondition(gvr, conditionType, conditionStatus, conditionReason, conditionMessage)
+			if got.verdict != want {
+				result.mismatches = append(result.mismatches, ruleTestMismatch{
+					object: key, conditionType: conditionType, got: got.verdict, step: got.step, want: want,
+					line: fmt.Sprintf("%s %s=%s %s %q", gvr.Resource, conditionType, conditionStatus, conditionReason, conditionMessage),
+				})
+			}
+		}
+		for conditionType, want := range expect {
+			result.mismatches = append(result.mismatches, ruleTestMismatch{object: key, conditionType: conditionType, want: want})
+		}
+	}
+	if len(sidecar) > 0 {
+		keys := make([]string, 0, len(sidecar))
+		for key := range sidecar {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		return result, fmt.Errorf("%s: no objects for %s", strings.TrimSuffix(path, filepath.Ext(path))+expectFileSuffix,
+			strings.Join(keys, ", "))
+	}
+	slices.SortFunc(result.mismatches, func(a, b ruleTestMismatch) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return result, nil
+}
+
+// fixtureKey returns Kind/namespace/name, or Kind/name for cluster-scoped
+// objects.
+func fixtureKey(obj unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetKind() + "/" + obj.GetName()
+	}
+	return obj.GetKind() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// parseExpectAnnotation parses the value of AnnotationExpect.
+func parseExpectAnnotation(value string) (map[string]string, error) {
+	expect := map[string]string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		conditionType, verdict, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s %q, expected Type=verdict", AnnotationExpect, item)
+		}
+		if !slices.Contains(verdicts, verdict) {
+			return nil, fmt.Errorf("invalid verdict %q, supported: %s", verdict, strings.Join(verdicts, ", "))
+		}
+		expect[conditionType] = verdict
+	}
+	return expect, nil
+}
+
+// readExpectFile reads a sidecar file, see expectFileSuffix. A missing file
+// is no error.
+func readExpectFile(path string) (map[string]map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var expect map[string]map[string]string
+	if err := yaml.UnmarshalStrict(data, &expect); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	for key, e := range expect {
+		for conditionType, verdict := range e {
+			if !slices.Contains(verdicts, verdict) {
+				return nil, fmt.Errorf("%s: %s %s: invalid verdict %q, supported: %s",
+					path, key, conditionType, verdict, strings.Join(verdicts, ", "))
+			}
+		}
+	}
+	if expect == nil {
+		expect = map[string]map[string]string{}
+	}
+	return expect, nil
+}
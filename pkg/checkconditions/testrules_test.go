@@ -0,0 +1,111 @@
+package checkconditions
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinRuleFixtures(t *testing.T) {
+	var out bytes.Buffer
+	failed, err := RunTestRules(&Arguments{}, "", []string{"testdata/rules"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed {
+		t.Fatalf("the builtin rules don't match the fixtures:\n%s", out.String())
+	}
+	for _, project := range []string{"capi", "longhorn", "flux", "cnpg", "percona", "rabbitmq"} {
+		if !strings.Contains(out.String(), "testdata/rules/"+project+"/") {
+			t.Errorf("no fixtures for %s", project)
+		}
+	}
+}
+
+const widgetFixture = `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  namespace: team-a
+  name: widget-a
+  annotations:
+    check-conditions.guettli.de/expect: Synced=healthy,Missing=healthy
+status:
+  conditions:
+  - type: Synced
+    status: "False"
+    reason: Waiting
+  - type: Ready
+    status: "True"
+  - type: Extra
+    status: "True"
+`
+
+func TestTestRulesMismatches(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "widgets.yaml"), widgetFixture)
+	writeFile(t, filepath.Join(dir, "widgets.expect.yaml"), "Widget/team-a/widget-a:\n  Ready: unhealthy\n")
+
+	var out bytes.Buffer
+	failed, err := RunTestRules(&Arguments{}, "", []string{dir}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !failed {
+		t.Fatalf("expected mismatches:\n%s", out.String())
+	}
+	for _, want := range []string{
+		"FAIL  " + filepath.Join(dir, "widgets.yaml") + "  2 conditions",
+		`--- Widget/team-a/widget-a: condition Missing is missing, want healthy`,
+		`--- Widget/team-a/widget-a: got healthy (positive suffix Ready), want unhealthy: widgets Ready=True  ""`,
+		`--- Widget/team-a/widget-a: got unhealthy (no rule matched), want healthy: widgets Synced=False Waiting ""`,
+		"FAIL: 3 mismatches, 2 conditions in 1 files, 1 conditions without expectation",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, out.String())
+		}
+	}
+
+	// A condition rule fixes Synced.
+	rules := filepath.Join(dir, "rules.txt")
+	writeFile(t, rules, "conditions:\n- resource: widgets\n  type: Synced\n  healthy: \"False\"\n")
+	out.Reset()
+	if _, err := RunTestRules(&Arguments{}, rules, []string{filepath.Join(dir, "widgets.yaml")}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "Synced") || !strings.Contains(out.String(), "0 rules, 1 condition rules") {
+		t.Errorf("expected the rules file to fix Synced:\n%s", out.String())
+	}
+}
+
+func TestTestRulesInvalidRulesFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "widgets.yaml"), widgetFixture)
+	rules := filepath.Join(dir, "rules.txt")
+	writeFile(t, rules, "conditions:\n- type: Synced\n  healthy: yes\n")
+	var out bytes.Buffer
+	failed, err := RunTestRules(&Arguments{}, rules, []string{dir}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !failed || !strings.HasPrefix(out.String(), "FAIL  "+rules) {
+		t.Fatalf("expected the rules file to fail:\n%s", out.String())
+	}
+}
+
+func TestTestRulesInvalidFixtures(t *testing.T) {
+	for name, files := range map[string]map[string]string{
+		"verdict":         {"w.yaml": strings.Replace(widgetFixture, "Synced=healthy", "Synced=fine", 1)},
+		"syntax":          {"w.yaml": strings.Replace(widgetFixture, "Synced=healthy", "Synced", 1)},
+		"sidecar-verdict": {"w.yaml": widgetFixture, "w.expect.yaml": "Widget/team-a/widget-a:\n  Ready: ok\n"},
+		"sidecar-object":  {"w.yaml": widgetFixture, "w.expect.yaml": "Widget/team-b/widget-a:\n  Ready: healthy\n"},
+	} {
+		dir := t.TempDir()
+		for file, content := range files {
+			writeFile(t, filepath.Join(dir, file), content)
+		}
+		if _, err := RunTestRules(&Arguments{}, "", []string{dir}, &bytes.Buffer{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
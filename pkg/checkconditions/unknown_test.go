@@ -0,0 +1,74 @@
+package checkconditions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUnknownStatus(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	unknownSince := func(d time.Duration) map[string]interface{} {
+		c := readyCondition("Unknown")
+		c["lastTransitionTime"] = now.Add(-d).Format(time.RFC3339)
+		return c
+	}
+	cluster := newTestCluster([]string{"team-a"},
+		newTestObject(nodesGVR, "Node", "", "restarting", unknownSince(40*time.Second)),
+		newTestObject(nodesGVR, "Node", "", "unreachable", unknownSince(5*time.Minute+time.Second)),
+		newTestObject(nodesGVR, "Node", "", "flaky", unknownSince(2*time.Minute)),
+		newTestObject(podsGVR, "Pod", "team-a", "pod-a", readyCondition("Unknown")),
+		newTestObject(podsGVR, "Pod", "team-a", "pod-b", readyCondition("False")),
+	)
+	args := &Arguments{Now: now, UnknownGrace: time.Minute}
+	counter, err := RunAndGetCounterWithClients(context.Background(), cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.UnknownInGrace != 1 || len(linesContaining(counter.Lines, "restarting")) != 0 {
+		t.Errorf("expected the node restarting for 40s to be in grace, got %d: %v", counter.UnknownInGrace, counter.Lines)
+	}
+	// pod-a has no lastTransitionTime, so it is never in grace.
+	if counter.UnknownFindings != 3 {
+		t.Errorf("expected 3 Unknown findings, got %d: %v", counter.UnknownFindings, counter.Lines)
+	}
+	for name, want := range map[string]Severity{
+		"unreachable": SeverityCritical,
+		"flaky":       SeverityWarning,
+		"pod-a":       SeverityWarning,
+		"pod-b":       SeverityWarning,
+	} {
+		found := false
+		for _, f := range counter.Findings {
+			if f.Name == name {
+				found = true
+				if f.Severity != want {
+					t.Errorf("%s: got severity %s, want %s", name, f.Severity, want)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s: finding is missing: %v", name, counter.Lines)
+		}
+	}
+
+	// Rules can use olderThan, too.
+	args = &Arguments{Now: now, Rules: []Rule{{Resource: "nodes", Status: "Unknown", OlderThan: &metav1.Duration{Duration: 90 * time.Second}, Severity: SeverityError}}}
+	counter, err = RunAndGetCounterWithClients(context.Background(), cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.UnknownInGrace != 0 || counter.UnknownFindings != 4 {
+		t.Errorf("expected no grace, got %d in grace and %d findings", counter.UnknownInGrace, counter.UnknownFindings)
+	}
+	for _, f := range counter.Findings {
+		if f.Name == "flaky" && f.Severity != SeverityError {
+			t.Errorf("expected the user rule to match flaky, got %s", f.Severity)
+		}
+		if f.Name == "restarting" && f.Severity != SeverityWarning {
+			t.Errorf("expected the user rule not to match restarting, got %s", f.Severity)
+		}
+	}
+}
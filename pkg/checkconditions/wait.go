@@ -0,0 +1,257 @@
+package checkconditions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ErrTimeout is returned if Arguments.Timeout was reached.
+var ErrTimeout = errors.New("timeout reached")
+
+// waitTarget is an object of the manifest given to RunWait.
+type waitTarget struct {
+	// gr is guessed from the kind, like groupByResource does.
+	gr        schema.GroupResource
+	namespace string
+	// defaultNamespace is used if the manifest has no namespace, see matches.
+	defaultNamespace string
+	name             string
+}
+
+func (t waitTarget) label() string {
+	return objectLabel(t.namespace, schema.GroupVersionResource{Group: t.gr.Group, Resource: t.gr.Resource}, t.name)
+}
+
+// matches reports whether a listed object is the target. A manifest object
+// without namespace matches cluster-scoped objects and objects in the
+// namespace "default", or in the namespace of a single -n, like kubectl
+// apply.
+func (t waitTarget) matches(ref objectRef) bool {
+	if ref.gvr.Group != t.gr.Group || ref.gvr.Resource != t.gr.Resource || ref.name != t.name {
+		return false
+	}
+	if t.namespace == "" {
+		return ref.namespace == "" || ref.namespace == t.defaultNamespace
+	}
+	return ref.namespace == t.namespace
+}
+
+func newWaitTargets(args *Arguments, objects []unstructured.Unstructured) []waitTarget {
+	defaultNamespace := "default"
+	if len(args.NamespacePatterns) == 1 && !patternHasGlob(args.NamespacePatterns[0]) {
+		defaultNamespace = args.NamespacePatterns[0]
+	}
+	var targets []waitTarget
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		if gvk.Kind == "" || obj.GetName() == "" {
+			continue
+		}
+		gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+		targets = append(targets, waitTarget{
+			gr: gvr.GroupResource(), namespace: obj.GetNamespace(), defaultNamespace: defaultNamespace, name: obj.GetName(),
+		})
+	}
+	return targets
+}
+
+// waitStatus is the state of one waitTarget after a scan.
+type waitStatus struct {
+	target waitTarget
+	found  bool
+	// skipped targets are of a type which is never checked, see
+	// resourcesToSkip. They don't block.
+	skipped bool
+	// descendants is the number of objects which are owned by the target,
+	// directly or indirectly.
+	descendants int
+	// findings of the target and its descendants which make it unhealthy.
+	findings []Finding
+	// groups are the API groups of the target and its descendants.
+	groups map[string]bool
+}
+
+func (s waitStatus) ready() bool {
+	return s.skipped || s.found && len(s.findings) == 0
+}
+
+func (s waitStatus) String() string {
+	switch {
+	case s.skipped:
+		return fmt.Sprintf("  SKIPPED %s (not checked)", s.target.label())
+	case !s.found:
+		return fmt.Sprintf("  MISSING %s", s.target.label())
+	case s.ready():
+		return fmt.Sprintf("  READY   %s (%d descendants)", s.target.label(), s.descendants)
+	}
+	return fmt.Sprintf("  WAITING %s (%d descendants, %d findings)", s.target.label(), s.descendants, len(s.findings))
+}
+
+// waitStatuses resolves the targets and their descendants (via
+// ownerReferences) in the listed objects, and assigns the findings to them.
+func waitStatuses(args *Arguments, targets []waitTarget, counter *Counter) []waitStatus {
+	children := map[types.UID][]objectRef{}
+	for _, o := range counter.objects {
+		for _, owner := range o.owners {
+			children[owner] = append(children[owner], o)
+		}
+	}
+	findingsByUID := map[types.UID][]Finding{}
+	for _, f := range counter.Findings {
+		if f.Severity == 0 || f.Severity >= args.failOn() {
+			findingsByUID[f.UID] = append(findingsByUID[f.UID], f)
+		}
+	}
+	statuses := make([]waitStatus, 0, len(targets))
+	for _, t := range targets {
+		s := waitStatus{target: t, groups: map[string]bool{t.gr.Group: true}}
+		if skipResourceType(schema.GroupVersionResource{Group: t.gr.Group, Resource: t.gr.Resource}) {
+			s.skipped = true
+			statuses = append(statuses, s)
+			continue
+		}
+		var queue []objectRef
+		for _, o := range counter.objects {
+			if t.matches(o) {
+				s.found = true
+				queue = append(queue, o)
+			}
+		}
+		seen := map[types.UID]bool{}
+		for len(queue) > 0 {
+			o := queue[0]
+			queue = queue[1:]
+			if o.uid == "" || seen[o.uid] {
+				continue
+			}
+			if len(seen) > 0 {
+				s.descendants++
+			}
+			seen[o.uid] = true
+			s.groups[o.gvr.Group] = true
+			s.findings = append(s.findings, findingsByUID[o.uid]...)
+			queue = append(queue, children[o.uid]...)
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// RunWait reads objects from manifests (see ReadObjects) and checks the
+// cluster until these objects and all objects they own (via
+// ownerReferences) have no findings. It prints the progress of each object
+// after each scan. If Arguments.Timeout is reached, it prints what is still
+// blocking and returns ErrTimeout.
+func RunWait(ctx context.Context, args *Arguments, paths []string, stdin io.Reader) error {
+	if err := args.rejectOutputFlags("wait"); err != nil {
+		return err
+	}
+	objects, err := ReadObjects(paths, stdin)
+	if err != nil {
+		return err
+	}
+	targets := newWaitTargets(args, objects)
+	if len(targets) == 0 {
+		return errors.New("no objects found in the manifests")
+	}
+	clients, err := args.clients()
+	if err != nil {
+		return err
+	}
+	args.collectOwners = true
+	// An object whose condition just became Unknown is not ready.
+	args.UnknownGrace = 0
+	var statuses []waitStatus
+	var listErrors []Finding
+	// groups are the API groups of the targets and their descendants of all
+	// scans so far.
+	groups := map[string]bool{}
+	for {
+		counter, err := runWithRetries(ctx, clients, args)
+		if errors.Is(err, ErrTimeout) && statuses != nil {
+			fmt.Printf("Still blocking after %s:\n", time.Since(args.ProgrammStartTime).Round(time.Second))
+			writeBlocking(os.Stdout, statuses)
+			writeListErrors(os.Stdout, listErrors)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		statuses = waitStatuses(args, targets, &counter)
+		for _, s := range statuses {
+			for group := range s.groups {
+				groups[group] = true
+			}
+		}
+		listErrors = blockingListErrors(counter.ListErrors, groups)
+		ready := 0
+		for _, s := range statuses {
+			if s.ready() {
+				ready++
+			}
+		}
+		fmt.Printf("%s: %d of %d objects are ready (%s)\n", time.Now().Format("2006-01-02 15:04:05 -0700 MST"),
+			ready, len(statuses), time.Since(args.ProgrammStartTime).Round(time.Second))
+		for _, s := range statuses {
+			fmt.Println(s.String())
+		}
+		// Descendants could be of a type which could not be listed.
+		writeListErrors(os.Stdout, listErrors)
+		if ready == len(statuses) && len(listErrors) == 0 {
+			args.printExpiredSilences(os.Stdout)
+			fmt.Println("All objects are ready.")
+			return nil
+		}
+		fmt.Println()
+		sleep := args.Sleep
+		if args.Timeout > 0 {
+			// Don't sleep past the timeout.
+			sleep = min(sleep, args.Timeout-time.Since(args.ProgrammStartTime)+time.Millisecond)
+		}
+		time.Sleep(sleep)
+	}
+}
+
+// blockingListErrors returns the list errors of the API groups of the
+// targets and their descendants. The objects of other groups, for example of
+// a broken metrics APIService, can't belong to the manifest.
+func blockingListErrors(listErrors []Finding, groups map[string]bool) []Finding {
+	var blocking []Finding
+	for _, f := range listErrors {
+		if groups[f.GVR.Group] {
+			blocking = append(blocking, f)
+		}
+	}
+	return blocking
+}
+
+// writeBlocking writes the objects which are not ready and their findings.
+func writeBlocking(w io.Writer, statuses []waitStatus) {
+	for _, s := range statuses {
+		if s.ready() {
+			continue
+		}
+		if !s.found {
+			fmt.Fprintf(w, "%s: not found\n", s.target.label())
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", s.target.label())
+		lines := make([]string, 0, len(s.findings))
+		for _, f := range s.findings {
+			lines = append(lines, f.Line())
+		}
+		slices.Sort(lines)
+		fmt.Fprintln(w, strings.Join(slices.Compact(lines), "\n"))
+	}
+}
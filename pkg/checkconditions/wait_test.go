@@ -0,0 +1,155 @@
+package checkconditions
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+const waitManifest = `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget-a
+  namespace: team-a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: team-a
+`
+
+func ownedPod(name, status string) *unstructured.Unstructured {
+	pod := newTestObject(podsGVR, "Pod", "team-a", name, readyCondition(status))
+	pod.SetUID(types.UID("uid-" + name))
+	pod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "example.com/v1", Kind: "Widget", Name: "widget-a", UID: "uid-widget-a"}})
+	return pod
+}
+
+func TestRunWait(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "app.yaml")
+	writeFile(t, manifest, waitManifest)
+	widget := newTestObject(widgetsGVR, "Widget", "team-a", "widget-a", readyCondition("True"))
+	widget.SetUID("uid-widget-a")
+	cluster := newTestCluster([]string{"team-a"}, widget, ownedPod("pod-a", "False"),
+		newTestObject(podsGVR, "Pod", "team-a", "unrelated", readyCondition("False")))
+
+	args := &Arguments{Clients: cluster.clients, ProgrammStartTime: time.Now(), Timeout: 50 * time.Millisecond, Sleep: 10 * time.Millisecond}
+	err := RunWait(context.Background(), args, []string{manifest}, nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a timeout because of the owned pod, got %v", err)
+	}
+
+	counter, err := RunAndGetCounterWithClients(context.Background(), cluster.clients, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := ReadObjects([]string{manifest}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := waitStatuses(args, newWaitTargets(args, objects), &counter)
+	if len(statuses) != 2 || statuses[0].ready() || statuses[0].descendants != 1 || len(statuses[0].findings) != 1 {
+		t.Fatalf("unexpected statuses %+v", statuses)
+	}
+	if !statuses[1].skipped {
+		t.Errorf("expected the ConfigMap to be skipped, got %+v", statuses[1])
+	}
+
+	// The unrelated pod does not block.
+	if err := cluster.dynamic.Tracker().Update(podsGVR, ownedPod("pod-a", "True"), "team-a"); err != nil {
+		t.Fatal(err)
+	}
+	args = &Arguments{Clients: cluster.clients, ProgrammStartTime: time.Now(), Timeout: time.Second}
+	if err := RunWait(context.Background(), args, []string{manifest}, nil); err != nil {
+		t.Fatalf("expected the widget to be ready, got %v", err)
+	}
+}
+
+func TestRunWaitIgnoresUnknownGrace(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "app.yaml")
+	writeFile(t, manifest, waitManifest)
+	widget := newTestObject(widgetsGVR, "Widget", "team-a", "widget-a", readyCondition("True"))
+	widget.SetUID("uid-widget-a")
+	pod := ownedPod("pod-a", "Unknown")
+	conditions, _, _ := unstructured.NestedSlice(pod.Object, "status", "conditions")
+	conditions[0].(map[string]interface{})["lastTransitionTime"] = time.Now().Format(time.RFC3339)
+	if err := unstructured.SetNestedSlice(pod.Object, conditions, "status", "conditions"); err != nil {
+		t.Fatal(err)
+	}
+	cluster := newTestCluster([]string{"team-a"}, widget, pod)
+
+	args := &Arguments{
+		Clients: cluster.clients, ProgrammStartTime: time.Now(), Timeout: 50 * time.Millisecond, Sleep: 10 * time.Millisecond,
+		UnknownGrace: time.Hour,
+	}
+	if err := RunWait(context.Background(), args, []string{manifest}, nil); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("a pod which just became Unknown should block, got %v", err)
+	}
+}
+
+func TestRunWaitListErrors(t *testing.T) {
+	podManifest := filepath.Join(t.TempDir(), "pod.yaml")
+	writeFile(t, podManifest, "apiVersion: v1\nkind: Pod\nmetadata:\n  name: pod-a\n  namespace: team-a\n")
+	widgetManifest := filepath.Join(t.TempDir(), "widget.yaml")
+	writeFile(t, widgetManifest, "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: widget-a\n  namespace: team-a\n")
+	cluster := newTestCluster([]string{"team-a"},
+		newTestObject(podsGVR, "Pod", "team-a", "pod-a", readyCondition("True")),
+		newTestObject(widgetsGVR, "Widget", "team-a", "widget-a", readyCondition("True")),
+	)
+	cluster.dynamic.PrependReactor("list", "widgets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewInternalError(errors.New("conversion webhook for example.com/v1, Kind=Widget failed: connection refused"))
+	})
+
+	// The broken widgets don't block a manifest without widgets.
+	args := &Arguments{Clients: cluster.clients, ProgrammStartTime: time.Now(), Timeout: time.Second}
+	out := captureStdout(t, func() {
+		if err := RunWait(context.Background(), args, []string{podManifest}, nil); err != nil {
+			t.Errorf("expected the pod to be ready, got %v", err)
+		}
+	})
+	if strings.Contains(out, "Errors listing") {
+		t.Errorf("list errors of other groups should not be shown:\n%s", out)
+	}
+
+	args = &Arguments{Clients: cluster.clients, ProgrammStartTime: time.Now(), Timeout: 50 * time.Millisecond, Sleep: 10 * time.Millisecond}
+	out = captureStdout(t, func() {
+		if err := RunWait(context.Background(), args, []string{widgetManifest}, nil); !errors.Is(err, ErrTimeout) {
+			t.Errorf("expected a timeout because widgets can't be listed, got %v", err)
+		}
+	})
+	_, report, _ := strings.Cut(out, "Still blocking")
+	if !strings.Contains(report, "Errors listing 1 resource types") || !strings.Contains(report, "widgets.example.com") {
+		t.Errorf("the timeout report should contain the list error:\n%s", out)
+	}
+}
+
+func TestRunWaitSingleNamespace(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "pod.yaml")
+	writeFile(t, manifest, "apiVersion: v1\nkind: Pod\nmetadata:\n  name: pod-a\n")
+	cluster := newTestCluster([]string{"team-a"}, newTestObject(podsGVR, "Pod", "team-a", "pod-a", readyCondition("True")))
+	args := &Arguments{Clients: cluster.clients, ProgrammStartTime: time.Now(), Timeout: time.Second, NamespacePatterns: []string{"team-a"}}
+	if err := RunWait(context.Background(), args, []string{manifest}, nil); err != nil {
+		t.Fatalf("the manifest object without namespace should be looked up in the -n namespace, got %v", err)
+	}
+}
+
+func TestRunWaitMissingObject(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "app.yaml")
+	writeFile(t, manifest, "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: not-yet-created\n  namespace: team-a\n")
+	cluster := defaultTestCluster()
+	args := &Arguments{Clients: cluster.clients, ProgrammStartTime: time.Now(), Timeout: 20 * time.Millisecond, Sleep: 10 * time.Millisecond}
+	if err := RunWait(context.Background(), args, []string{manifest}, nil); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+}